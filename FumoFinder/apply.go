@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/WhereIsF1/FumoFinder/internal/renamer" // Import the renamer package
+)
+
+// runApplyCommand carries out the renames of a plan written with --dry-run
+func runApplyCommand(args []string) {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	flags.Usage = printApplyHelp
+	journalPath := flags.String("journal", "", "Path to the rename journal (default: rename-journal.jsonl in the cache directory).")
+	onCollision := flags.String("on-collision", "skip", "What happens when files would get the same name: skip, suffix, keep-best or quarantine.")
+	mode := flags.String("mode", "rename", "How files get their new names: rename, move, hardlink, symlink or copy.")
+	quarantine := flags.String("quarantine", "", "Folder receiving duplicates (default: duplicates next to each file).")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		printApplyHelp()
+		os.Exit(2)
+	}
+
+	plan, err := renamer.LoadPlan(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading rename plan: %v", err)
+	}
+	fmt.Printf("✅	Loaded a rename plan with %d files created at %s.\n", len(plan.Entries), plan.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	collisionPolicy, err := renamer.ParseCollisionPolicy(*onCollision)
+	if err != nil {
+		log.Fatalf("Error parsing collision policy: %v", err)
+	}
+
+	outputMode, err := renamer.ParseOutputMode(*mode)
+	if err != nil {
+		log.Fatalf("Error parsing output mode: %v", err)
+	}
+
+	fileRenamer := renamer.NewFileRenamer("")
+	fileRenamer.SetOutputMode(outputMode)
+	fileRenamer.SetCollisionPolicy(collisionPolicy, *quarantine)
+	if journal, err := renamer.OpenJournal(*journalPath); err != nil {
+		log.Printf("Error opening rename journal: %v", err)
+	} else {
+		fileRenamer.SetJournal(journal)
+	}
+	fileRenamer.ApplyPlan(plan)
+}
+
+// printApplyHelp displays usage information for the apply command
+func printApplyHelp() {
+	fmt.Println(`Usage: FumoFinder apply [--mode <mode>] [--journal <path>] [--on-collision <policy>] [--quarantine <path>] <plan>
+
+Renames the files of a plan written with --dry-run. The plan may be edited by hand before applying it:
+change a target to rename a file differently, or set it to the source to leave the file alone.
+Files whose size or modification time changed since planning are skipped, and name collisions are checked again.
+The renames are recorded in the journal and can be reverted with FumoFinder undo.
+
+Example:
+  FumoFinder --input ./videos --dry-run --plan plan.csv
+  FumoFinder apply plan.csv`)
+}
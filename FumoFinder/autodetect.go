@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend"    // Import the backend package
+	"github.com/WhereIsF1/FumoFinder/internal/config"     // Import the config package
+	"github.com/WhereIsF1/FumoFinder/internal/identifier" // Import the identifier package
+	"github.com/WhereIsF1/FumoFinder/internal/prompt"     // Import the prompt package
+	"github.com/WhereIsF1/FumoFinder/internal/proxy"      // Import the proxy package
+)
+
+// detectAniListID sends a small probe sample without filter and determines the dominant AniList ID of the folder.
+// It returns the chosen ID (0 if none) and the probe matches that remain valid under it. Only the frames of these
+// matches are settled, all other probed frames have to be sent again with the filter.
+func detectAniListID(cfg *config.Config, frames []string, proxyDetails []proxy.ProxyDetails, searchBackend backend.SearchBackend) (int, []identifier.MatchInfo) {
+	probeFrames := identifier.SelectProbeFrames(frames, cfg.ProbeFiles, cfg.ProbeFrames)
+
+	fmt.Println(strings.Repeat("-", 50))
+	fmt.Printf("🔎	Probing %d frames to detect the AniList ID of the folder...\n", len(probeFrames))
+
+	// Run the probe without AniList filter
+	probe := identifier.NewEpisodeIdentifier(searchBackend, 0, proxyDetails)
+	probe.SetMatchFilter(newMatchFilter(cfg))
+	go probe.IdentifyEpisodes(probeFrames, cfg.Threshold)
+	probe.WaitForCompletion()
+
+	aniListID, title, share := identifier.DominantAniListID(probe.Matches)
+	if aniListID == 0 {
+		fmt.Println("⚠️	The probe did not find any matches. Proceeding without AniList filter.")
+		return 0, nil
+	}
+
+	fmt.Printf("🔎	Dominant AniList ID: %d (%s), %.0f%% of %d probe matches.\n", aniListID, title, share*100, len(probe.Matches))
+
+	// Accept automatically above the threshold, otherwise ask for confirmation
+	if share >= cfg.AutoAccept {
+		fmt.Printf("✅	Accepted AniList ID %d automatically.\n", aniListID)
+	} else if !confirmAniListID(aniListID) {
+		fmt.Println("⏭️	AniList ID rejected. Proceeding without AniList filter.")
+		return 0, probe.Matches
+	}
+
+	// Keep the probe matches of the chosen ID so their frames do not have to be sent again
+	var matches []identifier.MatchInfo
+	for _, match := range probe.Matches {
+		if match.AnilistID == aniListID {
+			matches = append(matches, match)
+		}
+	}
+	return aniListID, matches
+}
+
+// confirmAniListID asks the user whether to use the detected AniList ID as filter.
+// Without a terminal the prompt would consume piped input meant for later prompts, so the ID is not used.
+func confirmAniListID(aniListID int) bool {
+	if !prompt.IsTerminal() {
+		fmt.Println("⚠️	Standard input is not a terminal. Lower --auto-accept to use detected AniList IDs in unattended runs.")
+		return false
+	}
+	return prompt.YesNo(fmt.Sprintf("↪️	Do you want to use AniList ID %d as filter (y/n): ", aniListID))
+}
+
+// unsettledFrames returns the frames without a probe match, which still have to be identified
+func unsettledFrames(frames []string, probeMatches []identifier.MatchInfo) []string {
+	settled := make(map[string]bool)
+	for _, match := range probeMatches {
+		settled[filepath.Join(match.VideoName, match.FrameName)] = true
+	}
+
+	var remaining []string
+	for _, frame := range frames {
+		video, _ := identifier.FrameSource(frame)
+		if !settled[filepath.Join(video, filepath.Base(frame))] {
+			remaining = append(remaining, frame)
+		}
+	}
+	return remaining
+}
@@ -1,7 +1,5 @@
 // cmd/help.go
 
-// --api-key <key>	API key for trace.moe (optional).
-
 package main
 
 import (
@@ -29,11 +27,61 @@ Options:
   --ffprobe <path>	Path to the FFprobe executable (default: ffprobe system variable).
   --frames <number>	Number of frames to extract from each video, calculated as play duration divided by the frame count provided (default: 10).
   --anilist <id>	AniList ID to filter results (default: 0 - filter disabled). 
+  --auto-anilist		Detect the AniList ID of the folder with a small probe sample before the full identification (default: false).
+  --probe-files <number>	Number of files to probe when detecting the AniList ID (default: 3).
+  --probe-frames <number>	Number of frames per file to probe when detecting the AniList ID (default: 2).
+  --auto-accept <number>	Share of probe matches above which the detected AniList ID is accepted without confirmation (default: 0.8).
+  --anilist-map <path>	Path to a file mapping glob patterns or subfolders to AniList IDs (optional).
+  --api-key <key>	API key for trace.moe, sent as x-trace-key header (optional).
+  --backend-url <url>	Base URL of a self-hosted trace.moe instance, e.g. http://localhost:3311 (overrides the public API).
+  --fingerprints		Match frames against the local fingerprint index before searching and add confidently identified videos to it (default: false).
+  --fingerprint-index <path>	Path to the fingerprint index (default: fingerprints.json in the cache directory).
+  --fingerprint-distance <number>	Maximum number of differing hash bits for a frame to match the fingerprint index (default: 6).
+  --cut-borders		Let trace.moe cut black borders from the frames before searching (default: false).
+  --upload-mode <mode>	How frames are submitted to the search API: raw, multipart or url (default: raw).
+  --frame-server-addr <addr>	Listen address of the local frame server used by the url upload mode (default: 127.0.0.1:0).
+  --frame-server-url <url>	Public base URL of the local frame server, if the search API reaches it under a different address (required for the url mode without --backend-url).
+  --recursive		Include MKV files in subfolders of the input folder (default: false).
   --threshold <number>	Threshold in seconds for timestamp matching (default: 5.0).
+  --min-similarity <number>	Minimum similarity of a search result to be accepted as match (default: 0 - accept any similarity, 0.87 recommended).
+  --adult <mode>	How adult titles are treated: include, exclude or only (default: include).
   --no-cleanup		Do not clean up extracted frames after processing (default: false).
   --proxy <path>	Path to the file containing proxy addresses (optional - if not provided, no proxy is used).
+  --adaptive		Stop sending frames once a video is confidently identified and sample more frames for ambiguous videos (default: false).
+  --min-frames <number>	Minimum number of matched frames per video before adaptive mode stops sending frames (default: 4).
+  --max-frames <number>	Maximum number of frames per video in adaptive mode, including additional frames (default: 20).
+  --confidence <number>	Share of frames that must agree on an episode before adaptive mode stops sending frames (default: 0.9).
+  --trust-filename <number>	Stop sending frames for a video once this many matched frames agree with the episode in its file name (default: 0 - disabled).
+  --specials <path>	Path to a table mapping AniList IDs to S00 special episode numbers (optional).
+  --enrich		Enrich matches with AniList metadata such as format, year, season and episode titles, needed to recognise OVAs and specials (default: false).
+  --anilist-cache <path>	Directory for cached AniList responses (default: anilist in the cache directory).
+  --seasons		Detect season numbers through AniList relations and name files as Series.SxxEyy (default: false).
+  --anilist-api <url>	AniList GraphQL API endpoint (default: https://graphql.anilist.co).
+  --name-template <template>	Go text/template for the new file names, may contain / to move files into folders (default: classic Title.Exx naming).
+  --sanitize <rules>	Sanitisation rules for titles in file names: strict, safe or none (default: strict, or the rules of the preset).
+  --preset <name>	Naming preset generating folders and file names: plex, jellyfin, kodi or scene (optional).
+  --id-tags		Add AniList ID tags like [anilistid-12345] to the show folders of the jellyfin preset or .IDTag of custom templates (default: false).
+  --output <path>	Folder the new names are relative to, e.g. a media library (default: folder of each file).
+  --rename <policy>	Which files are renamed: never, ask, auto or auto-if-confident (default: ask).
+  --rename-confidence <value>	Minimum confidence for files to be renamed with --rename auto-if-confident (default: 0.90).
+  --dry-run		Write a rename plan instead of renaming, to be carried out later with FumoFinder apply (default: false).
+  --plan <path>		Path of the rename plan written by --dry-run, as CSV if it ends in .csv and JSON otherwise (default: rename-plan.json).
+  --mode <mode>		How files get their new names: rename, move, hardlink, symlink or copy (default: rename).
+  --library-root <path>	Library folder receiving the renamed files, required by --mode move (overrides --output).
+  --write-tags		Write title, show, episode, season and AniList ID tags into renamed MKV and MP4 files (default: false).
+  --nfo			Write Kodi/Jellyfin episodedetails NFO files next to renamed videos and tvshow.nfo files in their show folders (default: false).
+  --on-collision <policy>	What happens when files would get the same name: skip, suffix, keep-best or quarantine (default: skip).
+  --quarantine <path>	Folder receiving duplicates with --on-collision quarantine or keep-best (default: duplicates next to each file).
+  --journal <path>	Path to the rename journal used by FumoFinder undo (default: rename-journal.jsonl in the cache directory).
+  --episode-scheme <name>	Episode numbering used for the new file names: source, seasonal or absolute (default: source).
+  --episode-map <path>	Path to the episode mapping file (default: episode-mappings.json in the cache directory).
   --help, -h		Show this help message and exit.
 
+Commands:
+  index build|inspect|prune	Manage the local fingerprint index, see FumoFinder index for details.
+  apply <plan>			Rename the files of a plan written with --dry-run.
+  undo [--run <id>]		Revert the renames of the last run, or of the given run.
+
 Example:
   FumoFinder --input ./videos --frames 10
 
@@ -42,6 +90,7 @@ Example with a playtime of 23 minutes:
 
 Notice about the Frame Count:
   - For better accuracy, it is recommended to extract 10 or more frames per video.
+  - With --adaptive, easy videos stop early to save quota and ambiguous videos get additional frames up to --max-frames.
 
 Important Notes:
   - Please do not abuse the trace.moe API. Use it responsibly and consider supporting the project.
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/extractor"   // Import the extractor package
+	"github.com/WhereIsF1/FumoFinder/internal/fingerprint" // Import the fingerprint package
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"  // Import the identifier package
+	"github.com/WhereIsF1/FumoFinder/internal/model"       // Import the model package
+	"github.com/WhereIsF1/FumoFinder/internal/releasename" // Import the releasename package
+)
+
+// isCommand checks if FumoFinder was started with the given subcommand
+func isCommand(name string) bool {
+	return len(os.Args) > 1 && os.Args[1] == name
+}
+
+// runIndexCommand runs the build, inspect and prune subcommands of the fingerprint index
+func runIndexCommand(args []string) {
+	if len(args) == 0 {
+		printIndexHelp()
+		return
+	}
+
+	switch args[0] {
+	case "build":
+		buildIndex(args[1:])
+	case "inspect":
+		inspectIndex(args[1:])
+	case "prune":
+		pruneIndex(args[1:])
+	default:
+		fmt.Printf("❌	Unknown index command: %s\n", args[0])
+		printIndexHelp()
+		os.Exit(2)
+	}
+}
+
+// buildIndex fingerprints the frames of already identified videos, taking the episode from their file names
+func buildIndex(args []string) {
+	flags := flag.NewFlagSet("index build", flag.ExitOnError)
+	indexPath := flags.String("index", "", "Path to the fingerprint index (default: fingerprints.json in the cache directory).")
+	inputFolder := flags.String("input", "", "Path to the folder containing the identified MKV files (required).")
+	aniListID := flags.Int("anilist", 0, "AniList ID of the videos (required).")
+	title := flags.String("title", "", "Title of the show (default: title from the file names).")
+	numFrames := flags.Int("frames", 20, "Number of frames to fingerprint per video.")
+	ffmpegPath := flags.String("ffmpeg", "ffmpeg", "Path to the FFmpeg executable.")
+	ffprobePath := flags.String("ffprobe", "ffprobe", "Path to the FFprobe executable.")
+	flags.Parse(args)
+
+	if *inputFolder == "" || *aniListID == 0 {
+		fmt.Println("Input folder and AniList ID are required.")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	index, err := fingerprint.Load(*indexPath)
+	if err != nil {
+		log.Fatalf("Error loading fingerprint index: %v", err)
+	}
+
+	frameExtractor := extractor.NewFrameExtractor(*ffmpegPath, *ffprobePath, *numFrames)
+	frames, err := frameExtractor.ExtractFrames(*inputFolder)
+	if err != nil {
+		log.Fatalf("Error extracting frames: %v", err)
+	}
+	defer cleanupExtractedFrames(frameExtractor.ExtractedFrames())
+
+	var entries []fingerprint.Entry
+	skipped := make(map[string]bool)
+	for _, frame := range frames {
+		video, timestamp := identifier.FrameSource(frame)
+		release := releasename.Parse(video)
+		if release.Episode == "" {
+			skipped[video] = true
+			continue
+		}
+
+		hash, err := fingerprint.HashFile(frame)
+		if err != nil {
+			fmt.Printf("⚠️	%v\n", err)
+			continue
+		}
+
+		showTitle := *title
+		if showTitle == "" {
+			showTitle = release.Title
+		}
+		entries = append(entries, fingerprint.Entry{
+			Hash:      hash,
+			AniListID: *aniListID,
+			Title:     showTitle,
+			Episode:   release.Episode,
+			Timestamp: timestamp,
+			Video:     video,
+		})
+	}
+
+	for video := range skipped {
+		fmt.Printf("⏭️	Skipped %s: no episode number in the file name.\n", video)
+	}
+
+	added := index.Add(entries...)
+	if err := index.Save(); err != nil {
+		log.Fatalf("Error saving fingerprint index: %v", err)
+	}
+	fmt.Printf("✅	Added %d frames to the fingerprint index %s (%d frames in total).\n", added, index.Path(), index.Len())
+}
+
+// inspectIndex prints the shows and episodes stored in the index
+func inspectIndex(args []string) {
+	flags := flag.NewFlagSet("index inspect", flag.ExitOnError)
+	indexPath := flags.String("index", "", "Path to the fingerprint index (default: fingerprints.json in the cache directory).")
+	flags.Parse(args)
+
+	index, err := fingerprint.Load(*indexPath)
+	if err != nil {
+		log.Fatalf("Error loading fingerprint index: %v", err)
+	}
+
+	fmt.Printf("Fingerprint index: %s\n", index.Path())
+	fmt.Println(strings.Repeat("=", 50))
+	shows := index.Shows()
+	if len(shows) == 0 {
+		fmt.Println("The index is empty.")
+		return
+	}
+	for _, show := range shows {
+		fmt.Printf("AniList ID %-8d %s\n", show.AniListID, show.Title)
+		fmt.Printf("   - Episodes: %s\n", strings.Join(show.Episodes, ", "))
+		fmt.Printf("   - Frames  : %d\n", show.Frames)
+	}
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("%d shows, %d frames\n", len(shows), index.Len())
+}
+
+// pruneIndex removes entries by AniList ID, episode or age
+func pruneIndex(args []string) {
+	flags := flag.NewFlagSet("index prune", flag.ExitOnError)
+	indexPath := flags.String("index", "", "Path to the fingerprint index (default: fingerprints.json in the cache directory).")
+	aniListID := flags.Int("anilist", 0, "Remove the frames of this AniList ID.")
+	episode := flags.String("episode", "", "Remove only the frames of this episode (requires --anilist).")
+	olderThan := flags.Int("older-than", 0, "Remove frames added more than this many days ago.")
+	flags.Parse(args)
+
+	if *aniListID == 0 && *olderThan == 0 {
+		fmt.Println("At least one of --anilist or --older-than is required.")
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *episode != "" && *aniListID == 0 {
+		fmt.Println("--episode requires --anilist.")
+		os.Exit(2)
+	}
+
+	index, err := fingerprint.Load(*indexPath)
+	if err != nil {
+		log.Fatalf("Error loading fingerprint index: %v", err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -*olderThan)
+	removed := index.Prune(func(entry fingerprint.Entry) bool {
+		if *aniListID != 0 && entry.AniListID != *aniListID {
+			return false
+		}
+		if *episode != "" && entry.Episode != *episode {
+			return false
+		}
+		if *olderThan != 0 && !entry.AddedAt.Before(cutoff) {
+			return false
+		}
+		return true
+	})
+
+	if err := index.Save(); err != nil {
+		log.Fatalf("Error saving fingerprint index: %v", err)
+	}
+	fmt.Printf("✅	Removed %d frames from the fingerprint index (%d frames left).\n", removed, index.Len())
+}
+
+// recordFingerprints adds the frames of confidently identified videos to the index
+func recordFingerprints(index *fingerprint.Index, matches []identifier.MatchInfo) {
+	var entries []fingerprint.Entry
+	for _, match := range matches {
+		hash, err := fingerprint.HashFile(filepath.Join("frames", match.VideoName, match.FrameName))
+		if err != nil {
+			continue // Frames that cannot be decoded are not fingerprinted
+		}
+		entries = append(entries, fingerprint.Entry{
+			Hash:      hash,
+			AniListID: match.AnilistID,
+			Title:     match.DisplayTitle(),
+			Titles:    model.Title{Native: match.TitleNative, Romaji: match.TitleRomaji, English: match.TitleEnglish},
+			IsAdult:   match.IsAdult,
+			Episode:   match.Episode.String(),
+			Timestamp: match.Timestamp,
+			Video:     match.VideoName,
+		})
+	}
+
+	added := index.Add(entries...)
+	if err := index.Save(); err != nil {
+		log.Printf("Error saving fingerprint index: %v", err)
+		return
+	}
+	fmt.Printf("✅	Added %d frames of confidently identified videos to the fingerprint index.\n", added)
+}
+
+// printIndexHelp displays usage information for the index subcommands
+func printIndexHelp() {
+	fmt.Println(`Usage: FumoFinder index <command> [options]
+
+Commands:
+  build		Fingerprint the frames of identified videos, taking the episode from their file names.
+		--input <path> --anilist <id> [--title <title>] [--frames <number>] [--ffmpeg <path>] [--ffprobe <path>]
+  inspect	List the shows and episodes stored in the index.
+  prune		Remove frames by AniList ID, episode or age.
+		[--anilist <id>] [--episode <number>] [--older-than <days>]
+
+All commands accept --index <path> (default: fingerprints.json in the cache directory).
+
+Example:
+  FumoFinder index build --input ./Frieren --anilist 154587`)
+}
@@ -1,8 +1,7 @@
 // todo:
 // fix episode_identifier randomly dropping frames when proxies fails - just dont use bad proxies lol
 // fix some info collection not working properly
-// implement custom naming for files + somehow done - have to figure out how to implement season finding
-// implement api key usage option for trace.moe
+// implement custom naming for files + somehow done
 
 package main
 
@@ -15,11 +14,18 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/WhereIsF1/FumoFinder/internal/config"     // Import the config package
-	"github.com/WhereIsF1/FumoFinder/internal/extractor"  // Import the extractor package
-	"github.com/WhereIsF1/FumoFinder/internal/identifier" // Import the identifier package
-	"github.com/WhereIsF1/FumoFinder/internal/proxy"      // Import the proxy package
-	"github.com/WhereIsF1/FumoFinder/internal/renamer"    // Import the renamer package
+	"github.com/WhereIsF1/FumoFinder/internal/anilist"     // Import the anilist package
+	"github.com/WhereIsF1/FumoFinder/internal/backend"     // Import the backend package
+	"github.com/WhereIsF1/FumoFinder/internal/config"      // Import the config package
+	"github.com/WhereIsF1/FumoFinder/internal/extractor"   // Import the extractor package
+	"github.com/WhereIsF1/FumoFinder/internal/fingerprint" // Import the fingerprint package
+	"github.com/WhereIsF1/FumoFinder/internal/foldermap"   // Import the foldermap package
+	"github.com/WhereIsF1/FumoFinder/internal/frameserver" // Import the frameserver package
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"  // Import the identifier package
+	"github.com/WhereIsF1/FumoFinder/internal/mapping"     // Import the mapping package
+	"github.com/WhereIsF1/FumoFinder/internal/proxy"       // Import the proxy package
+	"github.com/WhereIsF1/FumoFinder/internal/renamer"     // Import the renamer package
+	"github.com/WhereIsF1/FumoFinder/internal/tagging"     // Import the tagging package
 )
 
 var (
@@ -29,6 +35,24 @@ var (
 )
 
 func main() {
+	// Manage the fingerprint index without running an identification
+	if isCommand("index") {
+		runIndexCommand(os.Args[2:])
+		return
+	}
+
+	// Carry out a rename plan written by a dry run
+	if isCommand("apply") {
+		runApplyCommand(os.Args[2:])
+		return
+	}
+
+	// Revert the renames of an earlier run
+	if isCommand("undo") {
+		runUndoCommand(os.Args[2:])
+		return
+	}
+
 	// Check if help is needed or no arguments are provided.
 	if len(os.Args) == 1 || hasHelpFlag() {
 		printHelpHeader()
@@ -45,18 +69,48 @@ func main() {
 	// Print the loaded configuration settings
 	printConfig(cfg)
 
+	// The public API cannot fetch frames from a listener on this machine
+	if cfg.UploadMode == string(backend.UploadURL) && cfg.FrameServerURL == "" && cfg.BackendURL == "" {
+		log.Fatalf("--upload-mode url requires --frame-server-url with an address the search API can reach, or a self-hosted --backend-url")
+	}
+
 	// Extract frames from each video file in the specified folder
 	frameExtractor := extractor.NewFrameExtractor(cfg.FfmpegPath, cfg.FfprobePath, cfg.NumFrames)
+	frameExtractor.SetRecursive(cfg.Recursive)
 	frames, err := frameExtractor.ExtractFrames(cfg.InputFolder)
 	if err != nil {
 		log.Fatalf("Error extracting frames: %v", err)
 	}
 
+	// Serve the frames over HTTP if the search API should fetch them by URL
+	var frameURLs backend.FrameURLProvider
+	if cfg.UploadMode == string(backend.UploadURL) {
+		frameServer, err := frameserver.Start("frames", cfg.FrameServerAddr, cfg.FrameServerURL)
+		if err != nil {
+			log.Fatalf("Error starting frame server: %v", err)
+		}
+		defer frameServer.Close()
+		frameURLs = frameServer
+	}
+	searchBackend := newSearchBackend(cfg, frameURLs)
+
+	// Match frames against the local fingerprint index before sending them to the search backend
+	var fingerprintIndex *fingerprint.Index
+	if cfg.Fingerprints {
+		fingerprintIndex, err = fingerprint.Load(cfg.FingerprintIndex)
+		if err != nil {
+			log.Fatalf("Error loading fingerprint index: %v", err)
+		}
+		fmt.Printf("✅	Loaded %d frames from the fingerprint index.\n", fingerprintIndex.Len())
+		searchBackend = backend.NewLocal(fingerprintIndex, cfg.FingerprintDistance, searchBackend)
+	}
+	fmt.Printf("🔌	Search backend: %s\n", searchBackend.Name())
+
 	// Initialize the proxy loader and load proxies after frame extraction
 	var proxies []*url.URL
 	if cfg.ProxyFilePath != "" {
 		// If the proxy file path is specified, load proxies
-		proxyLoader := proxy.NewProxyLoader()
+		proxyLoader := proxy.NewProxyLoader(searchBackend)
 		err := proxyLoader.LoadProxies(cfg.ProxyFilePath)
 		if err != nil {
 			log.Printf("Error loading proxies: %v", err)
@@ -78,11 +132,88 @@ func main() {
 		proxyDetails = append(proxyDetails, proxy.ProxyDetails{URL: p})
 	}
 
+	// Detect the AniList ID of the folder with a small probe sample if requested
+	aniListID := cfg.AniListID
+	var probeMatches []identifier.MatchInfo
+	if cfg.AutoAniList && aniListID == 0 {
+		aniListID, probeMatches = detectAniListID(cfg, frames, proxyDetails, searchBackend)
+
+		// Frames with a kept probe match do not need to be sent again
+		frames = unsettledFrames(frames, probeMatches)
+	}
+
 	// Initialize the episode identifier with the loaded proxies (or direct connection if none)
-	episodeIdentifier := identifier.NewEpisodeIdentifier(cfg.ApiEndpoint, cfg.AniListID, proxyDetails)
+	episodeIdentifier := identifier.NewEpisodeIdentifier(searchBackend, aniListID, proxyDetails)
+	episodeIdentifier.AddMatches(probeMatches)
+
+	// Apply the AniList ID filter per video from marker files and the mapping file
+	aniListResolver := foldermap.NewResolver(cfg.InputFolder, aniListID)
+	if cfg.AniListMap != "" {
+		if err := aniListResolver.LoadMappingFile(cfg.AniListMap); err != nil {
+			log.Printf("Error loading AniList mapping file: %v", err)
+		} else {
+			fmt.Printf("✅	Loaded %d AniList mapping rules.\n", len(aniListResolver.Rules()))
+		}
+	}
+	episodeIdentifier.SetAniListResolver(aniListResolver)
+
+	// Reject results below the similarity floor and filter adult titles
+	episodeIdentifier.SetMatchFilter(newMatchFilter(cfg))
+
+	// Enable the adaptive frame budget if requested
+	if cfg.Adaptive {
+		episodeIdentifier.EnableAdaptiveBudget(identifier.AdaptiveBudget{
+			MinFrames:        cfg.MinFrames,
+			MaxFrames:        cfg.MaxFrames,
+			ConfidenceTarget: cfg.Confidence,
+			Supplier:         frameExtractor,
+		})
+	}
+
+	// Skip API calls for videos whose file name agrees with a small sample
+	if cfg.TrustFilename > 0 {
+		episodeIdentifier.TrustFilenameHints(cfg.TrustFilename)
+	}
 
 	// Initialize the file renamer
 	fileRenamer := renamer.NewFileRenamer(cfg.InputFolder)
+	configureNaming(cfg, fileRenamer)
+	renamePolicy, err := renamer.ParseRenamePolicy(cfg.Rename)
+	if err != nil {
+		log.Fatalf("Error parsing rename policy: %v", err)
+	}
+	fileRenamer.SetRenamePolicy(renamePolicy, cfg.RenameConfidence)
+	collisionPolicy, err := renamer.ParseCollisionPolicy(cfg.OnCollision)
+	if err != nil {
+		log.Fatalf("Error parsing collision policy: %v", err)
+	}
+	fileRenamer.SetCollisionPolicy(collisionPolicy, cfg.Quarantine)
+	outputMode, err := renamer.ParseOutputMode(cfg.Mode)
+	if err != nil {
+		log.Fatalf("Error parsing output mode: %v", err)
+	}
+	if outputMode == renamer.ModeMove && cfg.LibraryRoot == "" && cfg.OutputFolder == "" {
+		log.Fatalf("--mode move requires --library-root")
+	}
+	fileRenamer.SetOutputMode(outputMode)
+	if cfg.WriteTags {
+		fileRenamer.SetTagger(tagging.NewTagger(cfg.FfmpegPath, cfg.FfprobePath))
+	}
+	fileRenamer.SetNFO(cfg.NFO)
+	if journal, err := renamer.OpenJournal(cfg.Journal); err != nil {
+		log.Printf("Error opening rename journal: %v", err)
+	} else {
+		fileRenamer.SetJournal(journal)
+	}
+	if cfg.SpecialsFile != "" {
+		specials, err := renamer.LoadSpecialsTable(cfg.SpecialsFile)
+		if err != nil {
+			log.Printf("Error loading specials table: %v", err)
+		} else {
+			fileRenamer.SetSpecialsTable(specials)
+			fmt.Printf("✅	Loaded %d specials table entries.\n", len(specials))
+		}
+	}
 
 	// Start the identification process in a separate goroutine
 	go episodeIdentifier.IdentifyEpisodes(frames, cfg.Threshold)
@@ -95,6 +226,38 @@ func main() {
 
 	fmt.Println(strings.Repeat("-", 50))
 	fmt.Println("✅	Episode identification completed.")
+	episodeIdentifier.DisplayRejectionSummary()
+
+	// Remember the frames of confidently identified videos for later runs
+	if fingerprintIndex != nil {
+		recordFingerprints(fingerprintIndex, episodeIdentifier.ConfidentMatches(identifier.ConfidenceWarningLevel))
+	}
+	// Set up the cached AniList client for metadata enrichment and season detection.
+	// trace.moe does not return the format, which is needed to name movies, OVAs and specials, so it is fetched whenever AniList is used.
+	// Without AniList, matches without an episode number are named as movies.
+	aniListClient := anilist.NewCachedClient(anilist.NewGraphQLClient(cfg.AniListAPI), cfg.AniListCache, anilist.DefaultCacheTTL)
+	if cfg.Enrich {
+		episodeIdentifier.EnrichMatches(aniListClient)
+	} else if cfg.Seasons && len(episodeIdentifier.Matches) > 0 {
+		episodeIdentifier.ClassifyMatches(aniListClient)
+	}
+	if cfg.Seasons {
+		fileRenamer.SetSeasonResolver(anilist.NewSeasonResolver(aniListClient))
+	}
+
+	// Load the episode mapping if a numbering scheme other than the source one is requested
+	if scheme, err := mapping.ParseScheme(cfg.EpisodeScheme); err != nil {
+		log.Printf("Error parsing episode scheme: %v", err)
+	} else if scheme != mapping.SchemeSource {
+		episodeMap, err := loadEpisodeMap(cfg.EpisodeMap)
+		if err != nil {
+			log.Printf("Error loading episode mapping: %v", err)
+		} else {
+			fmt.Printf("✅	Loaded %d episode mapping entries.\n", episodeMap.Len())
+		}
+		fileRenamer.SetEpisodeMapping(episodeMap, scheme)
+	}
+
 	// Check if matches are available and add them to the renamer
 	if len(episodeIdentifier.Matches) == 0 {
 		fmt.Println("⚠️	No matches found. Skipping renaming.")
@@ -104,18 +267,95 @@ func main() {
 			fileRenamer.AddResult(match) // Add MatchInfo to the file renamer
 		}
 
-		// Rename the files based on majority episode results
-		fmt.Println("🚀	Starting file renaming...")
-		fileRenamer.RenameFiles()
-		fmt.Println("✅	File renaming completed.")
+		if cfg.DryRun {
+			// Write the planned renames without touching the files
+			if err := fileRenamer.WritePlan(cfg.PlanFile); err != nil {
+				log.Printf("Error writing rename plan: %v", err)
+			}
+		} else {
+			// Rename the files based on majority episode results
+			fmt.Println("🚀	Starting file renaming...")
+			fileRenamer.RenameFiles()
+			fmt.Println("✅	File renaming completed.")
+		}
 	}
 
 	fmt.Println(strings.Repeat("=", 50))
 
 	// Perform cleanup if the no-cleanup flag is not set
 	if !cfg.NoCleanup {
-		cleanupExtractedFrames(frames)
+		cleanupExtractedFrames(frameExtractor.ExtractedFrames())
+	}
+}
+
+// newSearchBackend creates the search backend from the configuration, either the trace.moe API or a self-hosted instance
+func newSearchBackend(cfg *config.Config, frameURLs backend.FrameURLProvider) backend.SearchBackend {
+	uploadMode, err := backend.ParseUploadMode(cfg.UploadMode)
+	if err != nil {
+		log.Fatalf("Error parsing upload mode: %v", err)
+	}
+
+	traceMoeConfig := backend.TraceMoeConfig{
+		SearchURL:  cfg.ApiEndpoint,
+		APIKey:     cfg.APIKey,
+		CutBorders: cfg.CutBorders,
+		UploadMode: uploadMode,
+		FrameURLs:  frameURLs,
 	}
+	if cfg.BackendURL != "" {
+		return backend.NewSelfHostedTraceMoe(cfg.BackendURL, traceMoeConfig)
+	}
+	return backend.NewTraceMoe(traceMoeConfig)
+}
+
+// configureNaming sets the naming template, the sanitisation rules, the ID tags and the output folder of the renamer.
+// An explicit template or sanitisation rules take precedence over the preset.
+func configureNaming(cfg *config.Config, fileRenamer *renamer.FileRenamer) {
+	templateText, sanitizeRules, idTagForm := cfg.NameTemplate, cfg.Sanitize, renamer.DefaultIDTagForm
+	if cfg.Preset != "" {
+		preset, err := renamer.LookupPreset(cfg.Preset)
+		if err != nil {
+			log.Fatalf("Error loading naming preset: %v", err)
+		}
+		if templateText == "" {
+			templateText = preset.Template
+		}
+		if sanitizeRules == "" {
+			sanitizeRules = preset.Sanitize
+		}
+		idTagForm = preset.IDTagForm
+		if cfg.IDTags && idTagForm == "" {
+			fmt.Printf("ℹ️	The %s preset has no AniList ID tag its media server understands, ignoring --id-tags.\n", preset.Name)
+		}
+	}
+
+	sanitizer, err := renamer.ParseSanitizer(sanitizeRules)
+	if err != nil {
+		log.Fatalf("Error parsing sanitisation rules: %v", err)
+	}
+	nameTemplate, err := renamer.ParseNameTemplate(templateText, sanitizer)
+	if err != nil {
+		log.Fatalf("Error parsing naming template: %v", err)
+	}
+	fileRenamer.SetNameTemplate(nameTemplate)
+
+	if cfg.IDTags {
+		fileRenamer.SetIDTagFormat(idTagForm)
+	}
+	if cfg.LibraryRoot != "" {
+		fileRenamer.SetOutputFolder(cfg.LibraryRoot)
+	} else if cfg.OutputFolder != "" {
+		fileRenamer.SetOutputFolder(cfg.OutputFolder)
+	}
+}
+
+// newMatchFilter creates the similarity floor and adult filter from the configuration
+func newMatchFilter(cfg *config.Config) identifier.MatchFilter {
+	adultFilter, err := identifier.ParseAdultFilter(cfg.Adult)
+	if err != nil {
+		log.Fatalf("Error parsing adult filter: %v", err)
+	}
+	return identifier.MatchFilter{MinSimilarity: cfg.MinSimilarity, Adult: adultFilter}
 }
 
 // printHeader prints the ASCII art header
@@ -171,18 +411,92 @@ func printConfig(cfg *config.Config) {
 	fmt.Printf("FFmpeg Path     : %s\n", cfg.FfmpegPath)
 	fmt.Printf("FFprobe Path    : %s\n", cfg.FfprobePath)
 	fmt.Printf("Number of Frames: %d\n", cfg.NumFrames)
-	fmt.Printf("API Endpoint    : %s\n", cfg.ApiEndpoint)
+	if cfg.BackendURL != "" {
+		fmt.Printf("Backend URL     : %s (self-hosted trace.moe)\n", cfg.BackendURL)
+	} else {
+		fmt.Printf("API Endpoint    : %s\n", cfg.ApiEndpoint)
+	}
+	if cfg.APIKey != "" {
+		fmt.Printf("API Key         : set\n")
+	}
+	if cfg.Fingerprints {
+		fmt.Printf("Fingerprints    : enabled (max. distance %d)\n", cfg.FingerprintDistance)
+	}
 	if cfg.AniListID != 0 {
 		fmt.Printf("AniList ID      : %d\n", cfg.AniListID)
+	} else if cfg.AutoAniList {
+		fmt.Printf("AniList ID      : Auto-detect (%d files, %d frames each, accept at %.0f%%)\n", cfg.ProbeFiles, cfg.ProbeFrames, cfg.AutoAccept*100)
 	} else {
 		fmt.Printf("AniList ID      : Not specified\n")
 	}
+	if cfg.AniListMap != "" {
+		fmt.Printf("AniList Mapping : %s\n", cfg.AniListMap)
+	}
+	fmt.Printf("Recursive       : %t\n", cfg.Recursive)
+	fmt.Printf("Cut Borders     : %t\n", cfg.CutBorders)
+	fmt.Printf("Upload Mode     : %s\n", cfg.UploadMode)
 	fmt.Printf("Threshold       : %.2f seconds\n", cfg.Threshold)
+	fmt.Printf("Min. Similarity : %.0f%%\n", cfg.MinSimilarity*100)
+	fmt.Printf("Adult Titles    : %s\n", cfg.Adult)
+	if cfg.NameTemplate != "" {
+		fmt.Printf("Name Template   : %s\n", cfg.NameTemplate)
+	}
+	if cfg.Sanitize != "" {
+		fmt.Printf("Sanitize        : %s\n", cfg.Sanitize)
+	}
+	if cfg.Preset != "" {
+		fmt.Printf("Naming Preset   : %s (ID tags: %t)\n", cfg.Preset, cfg.IDTags)
+	}
+	if cfg.OutputFolder != "" {
+		fmt.Printf("Output Folder   : %s\n", cfg.OutputFolder)
+	}
+	if cfg.DryRun {
+		fmt.Printf("Dry Run         : writing plan to %s\n", cfg.PlanFile)
+	} else if cfg.Rename == "auto-if-confident" {
+		fmt.Printf("Rename Policy   : %s (%.0f%% confidence)\n", cfg.Rename, cfg.RenameConfidence*100)
+	} else {
+		fmt.Printf("Rename Policy   : %s\n", cfg.Rename)
+	}
+	fmt.Printf("Output Mode     : %s\n", cfg.Mode)
+	if cfg.LibraryRoot != "" {
+		fmt.Printf("Library Root    : %s\n", cfg.LibraryRoot)
+	}
+	fmt.Printf("On Collision    : %s\n", cfg.OnCollision)
+	fmt.Printf("Write Tags      : %t\n", cfg.WriteTags)
+	fmt.Printf("NFO Files       : %t\n", cfg.NFO)
+	if cfg.Quarantine != "" {
+		fmt.Printf("Quarantine      : %s\n", cfg.Quarantine)
+	}
+	if cfg.Journal != "" {
+		fmt.Printf("Rename Journal  : %s\n", cfg.Journal)
+	}
 	fmt.Printf("Cleanup         : %t\n", !cfg.NoCleanup)
 	fmt.Printf("Proxy File      : %s\n", cfg.ProxyFilePath)
+	if cfg.Adaptive {
+		fmt.Printf("Adaptive Frames : %d-%d frames, %.0f%% confidence target\n", cfg.MinFrames, cfg.MaxFrames, cfg.Confidence*100)
+	} else {
+		fmt.Printf("Adaptive Frames : Disabled\n")
+	}
+	if cfg.TrustFilename > 0 {
+		fmt.Printf("Trust Filename  : after %d agreeing frames\n", cfg.TrustFilename)
+	}
+	if cfg.SpecialsFile != "" {
+		fmt.Printf("Specials Table  : %s\n", cfg.SpecialsFile)
+	}
+	fmt.Printf("AniList Enrich  : %t\n", cfg.Enrich)
+	fmt.Printf("Season Detection: %t\n", cfg.Seasons)
+	fmt.Printf("Episode Scheme  : %s\n", cfg.EpisodeScheme)
 	fmt.Println(strings.Repeat("=", 50))
 }
 
+// loadEpisodeMap loads the episode mapping from the given path or from the cache directory
+func loadEpisodeMap(path string) (*mapping.Mapper, error) {
+	if path == "" {
+		path = mapping.DefaultPath()
+	}
+	return mapping.LoadMapper(path)
+}
+
 // CleanupExtractedFrames deletes the extracted frames after the run
 func cleanupExtractedFrames(frames []string) {
 	fmt.Println("\nPerforming cleanup...")
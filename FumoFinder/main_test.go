@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/config"
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"
+	"github.com/WhereIsF1/FumoFinder/internal/model"
+	"github.com/WhereIsF1/FumoFinder/internal/renamer"
+)
+
+func TestConfigureNamingPresets(t *testing.T) {
+	const video = "[Group] Sousou no Frieren - 05 [1080p].mkv"
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{"no preset", config.Config{}, "Sousou.no.Frieren.E05.mkv"},
+		{"plex", config.Config{Preset: "plex"}, "Sousou no Frieren (2023)/Season 01/Sousou no Frieren - S01E05.mkv"},
+		{"jellyfin", config.Config{Preset: "jellyfin"}, "Sousou no Frieren (2023)/Season 01/Sousou no Frieren - S01E05.mkv"},
+		{"jellyfin with ID tags", config.Config{Preset: "jellyfin", IDTags: true}, "Sousou no Frieren (2023) [anilistid-154587]/Season 01/Sousou no Frieren - S01E05.mkv"},
+		{"kodi", config.Config{Preset: "kodi"}, "Sousou no Frieren (2023)/Season 01/Sousou no Frieren - S01E05.mkv"},
+		{"scene", config.Config{Preset: "scene"}, "Sousou.no.Frieren.S01E05.mkv"},
+		{"preset with explicit rules", config.Config{Preset: "plex", Sanitize: "strict"}, "Sousou.no.Frieren (2023)/Season 01/Sousou.no.Frieren - S01E05.mkv"},
+		{"template overrides the preset", config.Config{Preset: "plex", NameTemplate: "{{.Title}} {{pad 3 .First}}"}, "Sousou no Frieren 005.mkv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, video), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			fileRenamer := renamer.NewFileRenamer(dir)
+			configureNaming(&tt.cfg, fileRenamer)
+			fileRenamer.AddResult(identifier.MatchInfo{
+				AnilistID:   154587,
+				TitleRomaji: "Sousou no Frieren",
+				Format:      "TV",
+				Year:        2023,
+				Episode:     model.EpisodeNumber{Number: 5, Raw: "5"},
+				Similarity:  95,
+				VideoName:   video,
+			})
+
+			planPath := filepath.Join(t.TempDir(), "plan.json")
+			if err := fileRenamer.WritePlan(planPath); err != nil {
+				t.Fatal(err)
+			}
+			plan, err := renamer.LoadPlan(planPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Entries) != 1 {
+				t.Fatalf("got %d planned renames, want 1", len(plan.Entries))
+			}
+
+			got, err := filepath.Rel(dir, plan.Entries[0].Target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.ToSlash(got) != tt.want {
+				t.Errorf("renamed to %q, want %q", filepath.ToSlash(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsettledFrames(t *testing.T) {
+	frame := func(video, name string) string { return filepath.Join("frames", video, name) }
+	frames := []string{
+		frame("Show - 05.mkv", "frame_0001_timestamp_00-02-00.jpg"),
+		frame("Show - 05.mkv", "frame_0002_timestamp_00-10-00.jpg"),
+		frame("Show - 06.mkv", "frame_0001_timestamp_00-02-00.jpg"),
+		frame("Show - 07.mkv", "frame_0001_timestamp_00-02-00.jpg"),
+	}
+	// Only the first frame of episode 5 kept its probe match, the other probed frames were rejected
+	probeMatches := []identifier.MatchInfo{{VideoName: "Show - 05.mkv", FrameName: "frame_0001_timestamp_00-02-00.jpg"}}
+
+	got := unsettledFrames(frames, probeMatches)
+	if len(got) != 3 || got[0] != frames[1] || got[1] != frames[2] || got[2] != frames[3] {
+		t.Errorf("unsettledFrames = %q, want every frame except %q", got, frames[0])
+	}
+}
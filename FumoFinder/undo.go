@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/WhereIsF1/FumoFinder/internal/renamer" // Import the renamer package
+)
+
+// runUndoCommand reverts the renames of a run recorded in the rename journal
+func runUndoCommand(args []string) {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	flags.Usage = printUndoHelp
+	journalPath := flags.String("journal", "", "Path to the rename journal (default: rename-journal.jsonl in the cache directory).")
+	runID := flags.String("run", "", "ID of the run to revert (default: the last run that was not reverted yet).")
+	list := flags.Bool("list", false, "List the runs recorded in the journal instead of reverting one.")
+	flags.Parse(args)
+
+	entries, err := renamer.LoadJournal(*journalPath)
+	if err != nil {
+		log.Fatalf("Error loading rename journal: %v", err)
+	}
+
+	if *list {
+		listRuns(entries)
+		return
+	}
+
+	if *runID == "" {
+		*runID = renamer.LastRun(entries)
+		if *runID == "" {
+			fmt.Println("⚠️	No renames to revert in the journal.")
+			return
+		}
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.RunID == *runID && entry.UndoOf == "" {
+			count++
+		}
+	}
+	if count == 0 {
+		fmt.Printf("❌	No renames recorded for run %s.\n", *runID)
+		os.Exit(1)
+	}
+
+	journal, err := renamer.OpenJournal(*journalPath)
+	if err != nil {
+		log.Fatalf("Error opening rename journal: %v", err)
+	}
+	fmt.Printf("↩️	Reverting %d renames of run %s...\n", count, *runID)
+	journal.UndoRun(entries, *runID)
+}
+
+// listRuns prints every run of the journal with its number of renames
+func listRuns(entries []renamer.JournalEntry) {
+	var runs []string
+	counts := make(map[string]int)
+	undone := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.UndoOf != "" {
+			undone[entry.UndoOf] = true
+			continue
+		}
+		if counts[entry.RunID] == 0 {
+			runs = append(runs, entry.RunID)
+		}
+		counts[entry.RunID]++
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("The journal is empty.")
+		return
+	}
+	for _, run := range runs {
+		status := ""
+		if undone[run] {
+			status = " (reverted)"
+		}
+		fmt.Printf("%s	%d renames%s\n", run, counts[run], status)
+	}
+}
+
+// printUndoHelp displays usage information for the undo command
+func printUndoHelp() {
+	fmt.Println(`Usage: FumoFinder undo [--run <id>] [--list] [--journal <path>]
+
+Reverts the renames of a run in reverse order, using the rename journal.
+Files that were changed, moved or replaced since they were renamed are left alone.
+
+Example:
+  FumoFinder undo --list
+  FumoFinder undo --run 20240101-120000.000`)
+}
@@ -0,0 +1,111 @@
+// internal/anilist/cache.go
+package anilist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached AniList responses are considered fresh
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+// cacheEntry is the on-disk format of a cached media
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Media     *Media    `json:"media"`
+}
+
+// CachedClient wraps a Client and caches its responses on disk
+type CachedClient struct {
+	client Client        // Client used when the cache has no fresh entry
+	dir    string        // Directory the cache files are stored in
+	ttl    time.Duration // How long cache entries stay fresh
+}
+
+// DefaultCacheDir returns the AniList cache directory inside the FumoFinder cache directory
+func DefaultCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(".", ".fumofinder-cache", "anilist")
+	}
+	return filepath.Join(cacheDir, "fumofinder", "anilist")
+}
+
+// NewCachedClient creates a new CachedClient storing responses in dir
+func NewCachedClient(client Client, dir string, ttl time.Duration) *CachedClient {
+	if dir == "" {
+		dir = DefaultCacheDir()
+	}
+	return &CachedClient{client: client, dir: dir, ttl: ttl}
+}
+
+// Media returns a cached media if it is still fresh, otherwise it fetches and caches it
+func (cc *CachedClient) Media(id int) (*Media, error) {
+	if media, ok := cc.load(id); ok {
+		return media, nil
+	}
+
+	media, err := cc.client.Media(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failing cache must not break the identification, so only log the error
+	if err := cc.store(id, media); err != nil {
+		fmt.Printf("⚠️ Failed to cache AniList media %d: %v\n", id, err)
+	}
+	return media, nil
+}
+
+// load reads a fresh cache entry for the given ID
+func (cc *CachedClient) load(id int) (*Media, bool) {
+	data, err := os.ReadFile(cc.path(id))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Media == nil {
+		return nil, false
+	}
+	if time.Since(entry.FetchedAt) > cc.ttl {
+		return nil, false
+	}
+	return entry.Media, true
+}
+
+// store writes a cache entry atomically through a temporary file
+func (cc *CachedClient) store(id int, media *Media) error {
+	if err := os.MkdirAll(cc.dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cacheEntry{FetchedAt: time.Now(), Media: media})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(cc.dir, "media-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), cc.path(id))
+}
+
+// path returns the cache file path of an AniList ID
+func (cc *CachedClient) path(id int) string {
+	return filepath.Join(cc.dir, strconv.Itoa(id)+".json")
+}
@@ -0,0 +1,168 @@
+// internal/anilist/client.go
+package anilist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for Title and FuzzyDate
+)
+
+// DefaultEndpoint is the public AniList GraphQL API
+const DefaultEndpoint = "https://graphql.anilist.co"
+
+// Client fetches media information from AniList
+type Client interface {
+	Media(id int) (*Media, error)
+}
+
+// Media holds the AniList information of a single anime
+type Media struct {
+	ID                int                `json:"id"`
+	IDMal             int                `json:"idMal"`
+	Type              string             `json:"type"`
+	Format            string             `json:"format"`
+	Title             model.Title        `json:"title"`
+	Synonyms          []string           `json:"synonyms"`
+	IsAdult           bool               `json:"isAdult"`
+	Episodes          int                `json:"episodes"`
+	Season            string             `json:"season"`
+	SeasonYear        int                `json:"seasonYear"`
+	StartDate         model.FuzzyDate    `json:"startDate"`
+	StreamingEpisodes []StreamingEpisode `json:"streamingEpisodes"`
+	Relations         struct {
+		Edges []RelationEdge `json:"edges"`
+	} `json:"relations"`
+}
+
+// StreamingEpisode holds the title of an episode as listed on a streaming site, e.g. "Episode 5 - The Title"
+type StreamingEpisode struct {
+	Title string `json:"title"`
+	Site  string `json:"site"`
+}
+
+// RelationEdge links a media to a related media, e.g. its PREQUEL or SEQUEL
+type RelationEdge struct {
+	RelationType string       `json:"relationType"`
+	Node         RelatedMedia `json:"node"`
+}
+
+// RelatedMedia holds the basic information of a related media
+type RelatedMedia struct {
+	ID     int         `json:"id"`
+	Type   string      `json:"type"`
+	Format string      `json:"format"`
+	Title  model.Title `json:"title"`
+}
+
+// EpisodeTitle returns the title of an episode from the streaming episode list, or an empty string if unknown
+func (m *Media) EpisodeTitle(episode string) string {
+	prefix := "Episode " + episode + " "
+	for _, streaming := range m.StreamingEpisodes {
+		if title, found := strings.CutPrefix(streaming.Title, prefix); found {
+			return strings.TrimSpace(strings.TrimLeft(title, "-:"))
+		}
+	}
+	return ""
+}
+
+// mediaQuery fetches a media together with its metadata and relations
+const mediaQuery = `query ($id: Int) {
+  Media(id: $id, type: ANIME) {
+    id
+    idMal
+    type
+    format
+    title { romaji english native }
+    synonyms
+    isAdult
+    episodes
+    season
+    seasonYear
+    startDate { year month day }
+    streamingEpisodes { title site }
+    relations {
+      edges {
+        relationType
+        node { id type format title { romaji english native } }
+      }
+    }
+  }
+}`
+
+// GraphQLClient queries the AniList GraphQL API
+type GraphQLClient struct {
+	endpoint   string       // GraphQL endpoint, configurable to point at a local stand-in
+	httpClient *http.Client // HTTP client used for the requests
+}
+
+// NewGraphQLClient creates a new GraphQLClient for the given endpoint
+func NewGraphQLClient(endpoint string) *GraphQLClient {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &GraphQLClient{
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Media fetches a media and its relations by AniList ID
+func (gc *GraphQLClient) Media(id int) (*Media, error) {
+	var data struct {
+		Media *Media `json:"Media"`
+	}
+	if err := gc.query(mediaQuery, map[string]any{"id": id}, &data); err != nil {
+		return nil, err
+	}
+	if data.Media == nil {
+		return nil, fmt.Errorf("anilist media %d not found", id)
+	}
+	return data.Media, nil
+}
+
+// query sends a GraphQL query and decodes the data field of the response into out
+func (gc *GraphQLClient) query(query string, variables map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode anilist query: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", gc.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request to anilist: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := gc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to anilist: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse anilist response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("anilist responded with an error: %s", result.Errors[0].Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("anilist responded with status code: %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("failed to parse anilist data: %v", err)
+	}
+	return nil
+}
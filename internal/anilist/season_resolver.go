@@ -0,0 +1,93 @@
+// internal/anilist/season_resolver.go
+package anilist
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxFranchiseDepth limits how many prequels are followed to protect against broken relation data
+const maxFranchiseDepth = 30
+
+// SeasonInfo holds the season of a media within its franchise
+type SeasonInfo struct {
+	Season      int    // Season number, counting only seasonal formats
+	SeriesTitle string // Canonical title of the whole franchise, taken from its first entry
+	RootID      int    // AniList ID of the first entry of the franchise
+}
+
+// SeasonResolver computes season numbers by walking AniList PREQUEL relations
+type SeasonResolver struct {
+	client Client             // AniList client used to fetch media
+	cache  map[int]SeasonInfo // Resolved seasons, keyed by AniList ID
+	mu     sync.Mutex         // Mutex to guard access to the cache
+}
+
+// NewSeasonResolver creates a new SeasonResolver using the given AniList client
+func NewSeasonResolver(client Client) *SeasonResolver {
+	return &SeasonResolver{
+		client: client,
+		cache:  make(map[int]SeasonInfo),
+	}
+}
+
+// Resolve determines the season number and canonical series title of an AniList ID
+func (sr *SeasonResolver) Resolve(id int) (SeasonInfo, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if info, ok := sr.cache[id]; ok {
+		return info, nil
+	}
+
+	media, err := sr.client.Media(id)
+	if err != nil {
+		return SeasonInfo{}, err
+	}
+
+	// Walk back through the prequels; movies, OVAs and specials in between do not count as seasons
+	season := 1
+	root, seriesRoot := media, media
+	visited := map[int]bool{media.ID: true}
+	for depth := 0; depth < maxFranchiseDepth; depth++ {
+		prequel := findRelation(root, "PREQUEL")
+		if prequel == nil || visited[prequel.ID] {
+			break
+		}
+		visited[prequel.ID] = true
+
+		if isSeasonalFormat(prequel.Format) {
+			season++
+		}
+
+		root, err = sr.client.Media(prequel.ID)
+		if err != nil {
+			return SeasonInfo{}, fmt.Errorf("failed to follow prequel %d of %d: %v", prequel.ID, id, err)
+		}
+
+		// Prefer the first seasonal entry as root so a prequel movie does not name the series
+		if isSeasonalFormat(root.Format) {
+			seriesRoot = root
+		}
+	}
+
+	title := seriesRoot.Title
+	info := SeasonInfo{Season: season, SeriesTitle: title.Preferred(), RootID: seriesRoot.ID}
+	sr.cache[id] = info
+	return info, nil
+}
+
+// findRelation returns the first related anime with the given relation type
+func findRelation(media *Media, relationType string) *RelatedMedia {
+	for _, edge := range media.Relations.Edges {
+		if edge.RelationType == relationType && edge.Node.Type == "ANIME" {
+			return &edge.Node
+		}
+	}
+	return nil
+}
+
+// isSeasonalFormat reports whether a format counts as a season of a series
+func isSeasonalFormat(format string) bool {
+	return format == "TV" || format == "TV_SHORT"
+}
@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned when the search API answered but refused a request, e.g. because of rate limits or a used up quota.
+// It tells these refusals apart from failing connections and proxies.
+type APIError struct {
+	StatusCode int           // HTTP status code of the response
+	Message    string        // Error message of the response (empty if none was sent)
+	RetryAfter time.Duration // Delay requested by the Retry-After header (0 if not sent)
+}
+
+// Error returns the status code and message of the refusal
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("search API responded with status code %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("search API responded with status code: %d", e.StatusCode)
+}
+
+// RateLimited reports whether too many requests were sent in a short time (429)
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// QuotaExhausted reports whether the search quota of the account or IP address is used up (402)
+func (e *APIError) QuotaExhausted() bool {
+	return e.StatusCode == http.StatusPaymentRequired
+}
+
+// Temporary reports whether the same request may succeed later: rate limits and server errors
+func (e *APIError) Temporary() bool {
+	return e.RateLimited() || e.StatusCode >= 500
+}
+
+// newAPIError creates an APIError from a response, reading the delay from the Retry-After header
+func newAPIError(resp *http.Response, message string) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: message}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(retryAfter); err == nil {
+			apiErr.RetryAfter = max(time.Until(date), 0)
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,35 @@
+// internal/backend/backend.go
+package backend
+
+import (
+	"net/http"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for TraceMoeResponse
+)
+
+// SearchOptions holds the per-frame options of a search
+type SearchOptions struct {
+	AniListID int // AniList ID to restrict the search to (0 - no restriction)
+}
+
+// Quota holds the quota information of the account or IP address used for searching
+type Quota struct {
+	ID          string `json:"id"`
+	Priority    int    `json:"priority"`
+	Concurrency int    `json:"concurrency"`
+	Quota       int    `json:"quota"`
+	QuotaUsed   int    `json:"quotaUsed"`
+}
+
+// Remaining returns the number of searches left in the quota
+func (q *Quota) Remaining() int {
+	return q.Quota - q.QuotaUsed
+}
+
+// SearchBackend searches frames and reports the remaining quota.
+// The HTTP client is passed in so the requests go through the proxy chosen by the caller.
+type SearchBackend interface {
+	Name() string
+	Search(client *http.Client, framePath string, options SearchOptions) (*model.TraceMoeResponse, error)
+	Quota(client *http.Client) (*Quota, error)
+}
@@ -0,0 +1,87 @@
+// internal/backend/local.go
+package backend
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/WhereIsF1/FumoFinder/internal/fingerprint" // Import the fingerprint package for the local index
+	"github.com/WhereIsF1/FumoFinder/internal/model"       // Import the model package for TraceMoeResponse
+)
+
+// Local is a SearchBackend matching frames against the local fingerprint index,
+// falling back to another backend for frames without a match
+type Local struct {
+	index       *fingerprint.Index
+	maxDistance int           // Maximum number of differing hash bits for a match
+	fallback    SearchBackend // Backend for frames not found in the index, nil to search the index only
+}
+
+// NewLocal creates a local backend for the index with an optional fallback backend
+func NewLocal(index *fingerprint.Index, maxDistance int, fallback SearchBackend) *Local {
+	return &Local{index: index, maxDistance: maxDistance, fallback: fallback}
+}
+
+// Name returns the name of the backend
+func (l *Local) Name() string {
+	name := "local fingerprint index (" + l.index.Path() + ")"
+	if l.fallback != nil {
+		name += ", falling back to " + l.fallback.Name()
+	}
+	return name
+}
+
+// Search looks the frame up in the index and returns the stored frame as a trace.moe style result
+func (l *Local) Search(client *http.Client, framePath string, options SearchOptions) (*model.TraceMoeResponse, error) {
+	hash, err := fingerprint.HashFile(framePath)
+	if err == nil {
+		if entry, distance, found := l.index.Lookup(hash, options.AniListID, l.maxDistance); found {
+			return localResponse(entry, distance), nil
+		}
+	}
+
+	// Frames that cannot be decoded or are not in the index are searched by the fallback
+	if l.fallback != nil {
+		return l.fallback.Search(client, framePath, options)
+	}
+	return &model.TraceMoeResponse{}, nil
+}
+
+// Quota reports the quota of the fallback backend; the index alone has no quota
+func (l *Local) Quota(client *http.Client) (*Quota, error) {
+	if l.fallback != nil {
+		return l.fallback.Quota(client)
+	}
+	return &Quota{ID: "local", Quota: math.MaxInt32}, nil
+}
+
+// localResponse converts an index entry into a search response
+func localResponse(entry fingerprint.Entry, distance int) *model.TraceMoeResponse {
+	// Frames indexed from file names only know the display title
+	titles := entry.Titles
+	if titles == (model.Title{}) {
+		titles.Romaji = entry.Title
+	}
+
+	episode := model.EpisodeNumber{Raw: entry.Episode}
+	if number, err := strconv.ParseFloat(entry.Episode, 64); err == nil {
+		episode.Number = number
+	}
+
+	return &model.TraceMoeResponse{
+		FrameCount: 1,
+		Result: []model.TraceMoeResult{{
+			Anilist: model.AnilistInfo{
+				ID:      entry.AniListID,
+				Title:   titles,
+				IsAdult: entry.IsAdult,
+			},
+			Filename:   entry.Video,
+			Episode:    episode,
+			From:       entry.Timestamp,
+			To:         entry.Timestamp,
+			Similarity: 1 - float64(distance)/64,
+		}},
+	}
+}
@@ -0,0 +1,162 @@
+// internal/backend/tracemoe.go
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for TraceMoeResponse
+)
+
+// DefaultTraceMoeURL is the base URL of the public trace.moe API
+const DefaultTraceMoeURL = "https://api.trace.moe"
+
+// userAgent mimics a browser request for better compatibility - not necessary for api.trace.moe but may be useful for other "APIs" that require it
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3"
+
+// TraceMoeConfig configures a trace.moe backend, either the public API or a self-hosted instance
+type TraceMoeConfig struct {
+	SearchURL  string           // Search endpoint, e.g. https://api.trace.moe/search
+	MeURL      string           // Quota endpoint, derived from the search endpoint if empty
+	APIKey     string           // API key sent as x-trace-key header (optional)
+	CutBorders bool             // Whether trace.moe should cut black borders from frames
+	UploadMode UploadMode       // How frames are submitted to the search endpoint
+	FrameURLs  FrameURLProvider // Source of frame URLs for the url upload mode
+}
+
+// TraceMoe is a SearchBackend for the trace.moe API
+type TraceMoe struct {
+	config TraceMoeConfig
+}
+
+// NewTraceMoe creates a new trace.moe backend
+func NewTraceMoe(config TraceMoeConfig) *TraceMoe {
+	if config.SearchURL == "" {
+		config.SearchURL = DefaultTraceMoeURL + "/search"
+	}
+	if config.MeURL == "" {
+		config.MeURL = deriveMeURL(config.SearchURL)
+	}
+	if config.UploadMode == "" {
+		config.UploadMode = UploadRaw
+	}
+	return &TraceMoe{config: config}
+}
+
+// NewSelfHostedTraceMoe creates a trace.moe backend for a self-hosted instance with its own base URL
+func NewSelfHostedTraceMoe(baseURL string, config TraceMoeConfig) *TraceMoe {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	config.SearchURL = baseURL + "/search"
+	config.MeURL = baseURL + "/me"
+	return NewTraceMoe(config)
+}
+
+// deriveMeURL replaces the /search path of a search endpoint with /me
+func deriveMeURL(searchURL string) string {
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		return DefaultTraceMoeURL + "/me"
+	}
+	parsed.Path = strings.TrimSuffix(strings.TrimSuffix(parsed.Path, "/"), "/search") + "/me"
+	parsed.RawQuery = ""
+	return parsed.String()
+}
+
+// Name returns the name of the backend
+func (tm *TraceMoe) Name() string {
+	return "trace.moe (" + tm.config.SearchURL + ")"
+}
+
+// Search sends a frame to trace.moe and returns the parsed response
+func (tm *TraceMoe) Search(client *http.Client, framePath string, options SearchOptions) (*model.TraceMoeResponse, error) {
+	searchURL, err := tm.buildSearchURL(options.AniListID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newSearchRequest(tm.config.UploadMode, searchURL, framePath, tm.config.FrameURLs)
+	if err != nil {
+		return nil, err
+	}
+	tm.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send frame to trace.moe: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result model.TraceMoeResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		// Refusals like rate limits come with an error message, gateways may answer with an HTML page instead
+		return nil, newAPIError(resp, result.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace.moe response: %v", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("trace.moe responded with an error: %s", result.Error)
+	}
+
+	return &result, nil
+}
+
+// Quota queries the /me endpoint for the quota status
+func (tm *TraceMoe) Quota(client *http.Client) (*Quota, error) {
+	req, err := http.NewRequest("GET", tm.config.MeURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	tm.setHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check if the status code is OK
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responded with status code: %d", resp.StatusCode)
+	}
+
+	var quota Quota
+	if err := json.NewDecoder(resp.Body).Decode(&quota); err != nil {
+		return nil, fmt.Errorf("failed to parse /me endpoint response: %v", err)
+	}
+	return &quota, nil
+}
+
+// buildSearchURL composes the search URL, adding the anilistInfo, anilistID and cutBorders parameters
+func (tm *TraceMoe) buildSearchURL(aniListID int) (string, error) {
+	searchURL, err := url.Parse(tm.config.SearchURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid API endpoint %s: %v", tm.config.SearchURL, err)
+	}
+
+	query := searchURL.Query()
+	query.Set("anilistInfo", "")
+	if aniListID != 0 {
+		// Let trace.moe search within the show only; the client-side check stays as a safety net
+		query.Set("anilistID", strconv.Itoa(aniListID))
+	}
+	if tm.config.CutBorders {
+		query.Set("cutBorders", "")
+	}
+	searchURL.RawQuery = query.Encode()
+
+	return searchURL.String(), nil
+}
+
+// setHeaders sets the user agent and, if configured, the API key
+func (tm *TraceMoe) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", userAgent)
+	if tm.config.APIKey != "" {
+		req.Header.Set("x-trace-key", tm.config.APIKey)
+	}
+}
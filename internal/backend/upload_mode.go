@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadMode selects how frames are submitted to the search API
+type UploadMode string
+
+const (
+	UploadRaw       UploadMode = "raw"       // POST the raw JPEG body with Content-Type: image/jpeg
+	UploadMultipart UploadMode = "multipart" // POST a multipart form with the frame in the "image" field
+	UploadURL       UploadMode = "url"       // GET with ?url= pointing at the frame on the local frame server
+)
+
+// ParseUploadMode parses an upload mode name
+func ParseUploadMode(name string) (UploadMode, error) {
+	switch mode := UploadMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case UploadRaw, UploadMultipart, UploadURL:
+		return mode, nil
+	case "":
+		return UploadRaw, nil
+	default:
+		return "", fmt.Errorf("unknown upload mode: %s (expected raw, multipart or url)", name)
+	}
+}
+
+// FrameURLProvider returns a URL under which the search API can fetch a frame
+type FrameURLProvider interface {
+	URLFor(framePath string) (string, error)
+}
+
+// newSearchRequest creates the search request for a frame using the given upload mode
+func newSearchRequest(mode UploadMode, searchURL string, imagePath string, frameURLs FrameURLProvider) (*http.Request, error) {
+	if mode == UploadURL {
+		if frameURLs == nil {
+			return nil, fmt.Errorf("url upload mode requires a frame server")
+		}
+		frameURL, err := frameURLs.URLFor(imagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := url.Parse(searchURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search URL %s: %v", searchURL, err)
+		}
+		query := parsed.Query()
+		query.Set("url", frameURL)
+		parsed.RawQuery = query.Encode()
+
+		req, err := http.NewRequest("GET", parsed.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request to trace.moe: %v", err)
+		}
+		return req, nil
+	}
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame: %v", err)
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	contentType := "image/jpeg"
+	if mode == UploadMultipart {
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreateFormFile("image", filepath.Base(imagePath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create multipart form: %v", err)
+		}
+		if _, err := io.Copy(part, file); err != nil {
+			return nil, fmt.Errorf("failed to read frame: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to create multipart form: %v", err)
+		}
+		contentType = writer.FormDataContentType()
+	} else if _, err := buf.ReadFrom(file); err != nil {
+		return nil, fmt.Errorf("failed to read frame: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", searchURL, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request to trace.moe: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
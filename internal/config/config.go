@@ -4,20 +4,77 @@ package config
 import (
 	"flag"
 	"fmt"
+
+	"github.com/WhereIsF1/FumoFinder/internal/fingerprint" // Import the fingerprint package for the default hash distance
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"  // Import the identifier package for the recommended similarity floor
 )
 
 // Config holds the application's configuration settings
 type Config struct {
-	InputFolder string
-	FfmpegPath  string
-	FfprobePath string
-	NumFrames   int
-	// apikey        string
-	ApiEndpoint   string
-	AniListID     int
-	Threshold     float64
-	NoCleanup     bool
-	ProxyFilePath string
+	InputFolder     string
+	FfmpegPath      string
+	FfprobePath     string
+	NumFrames       int
+	ApiEndpoint     string
+	AniListID       int
+	Threshold       float64
+	NoCleanup       bool
+	ProxyFilePath   string
+	Adaptive        bool
+	MinFrames       int
+	MaxFrames       int
+	Confidence      float64
+	TrustFilename   int
+	AutoAniList     bool
+	ProbeFiles      int
+	ProbeFrames     int
+	AutoAccept      float64
+	AniListMap      string
+	Recursive       bool
+	CutBorders      bool
+	UploadMode      string
+	FrameServerAddr string
+	FrameServerURL  string
+	SpecialsFile    string
+	Seasons         bool
+	AniListAPI      string
+	Enrich          bool
+	AniListCache    string
+	EpisodeScheme   string
+	EpisodeMap      string
+	APIKey          string
+	BackendURL      string
+
+	Fingerprints        bool
+	FingerprintIndex    string
+	FingerprintDistance int
+
+	MinSimilarity float64
+	Adult         string
+
+	NameTemplate string
+	Sanitize     string
+	Preset       string
+	IDTags       bool
+	OutputFolder string
+
+	Rename           string
+	RenameConfidence float64
+
+	DryRun   bool
+	PlanFile string
+
+	Journal string
+
+	OnCollision string
+	Quarantine  string
+
+	Mode        string
+	LibraryRoot string
+
+	WriteTags bool
+
+	NFO bool
 }
 
 // LoadConfig parses the command-line arguments and returns a Config struct
@@ -26,12 +83,86 @@ func LoadConfig() *Config {
 	ffmpegPath := flag.String("ffmpeg", "ffmpeg", "Path to the FFmpeg executable.")                                                                      // Define the FFmpeg path flag
 	ffprobePath := flag.String("ffprobe", "ffprobe", "Path to the FFprobe executable.")                                                                  // Define the FFprobe path flag
 	numFrames := flag.Int("frames", 10, "Number of frames to extract from each video, calculated as play duration divided by the frame count provided.") // Define the number of frames flag
-	// apikey := flag.String("api-key", "", "API key for trace.moe")                                  																   // Define the API key flag
-	apiEndpoint := flag.String("api", "https://api.trace.moe/search?anilistInfo", "API endpoint for trace.moe")                          // Define the API endpoint flag
-	aniListID := flag.Int("anilist", 0, "AniList ID to filter results (default: 0 - filter disabled). ")                                 // Define the AniList ID flag
-	threshold := flag.Float64("threshold", 5.0, "Threshold in seconds for timestamp matching.")                                          // Define the threshold flag
-	noCleanup := flag.Bool("no-cleanup", false, "Do not clean up extracted frames after processing.")                                    // Define the no-cleanup flag
-	proxyFile := flag.String("proxy", "", "Path to the file containing proxy addresses (optional - if not provided, no proxy is used).") // Define the proxy file flag
+	apiEndpoint := flag.String("api", "https://api.trace.moe/search", "API endpoint for trace.moe")                                                      // Define the API endpoint flag
+	aniListID := flag.Int("anilist", 0, "AniList ID to filter results (default: 0 - filter disabled). ")                                                 // Define the AniList ID flag
+	threshold := flag.Float64("threshold", 5.0, "Threshold in seconds for timestamp matching.")                                                          // Define the threshold flag
+	noCleanup := flag.Bool("no-cleanup", false, "Do not clean up extracted frames after processing.")                                                    // Define the no-cleanup flag
+	proxyFile := flag.String("proxy", "", "Path to the file containing proxy addresses (optional - if not provided, no proxy is used).")                 // Define the proxy file flag
+
+	// Adaptive frame budget
+	adaptive := flag.Bool("adaptive", false, "Stop sending frames once a video is confidently identified and sample more frames for ambiguous videos.")                              // Define the adaptive flag
+	minFrames := flag.Int("min-frames", 4, "Minimum number of matched frames per video before adaptive mode stops sending frames.")                                                  // Define the minimum frames flag
+	maxFrames := flag.Int("max-frames", 20, "Maximum number of frames per video in adaptive mode, including additional frames.")                                                     // Define the maximum frames flag
+	confidence := flag.Float64("confidence", 0.9, "Share of frames that must agree on an episode before adaptive mode stops sending frames.")                                        // Define the confidence target flag
+	trustFilename := flag.Int("trust-filename", 0, "Stop sending frames for a video once this many matched frames agree with the episode in its file name (default: 0 - disabled).") // Define the trust filename flag
+
+	// AniList ID detection
+	autoAniList := flag.Bool("auto-anilist", false, "Detect the AniList ID of the folder with a small probe sample before the full identification.")            // Define the auto AniList flag
+	probeFiles := flag.Int("probe-files", 3, "Number of files to probe when detecting the AniList ID.")                                                         // Define the probe files flag
+	probeFrames := flag.Int("probe-frames", 2, "Number of frames per file to probe when detecting the AniList ID.")                                             // Define the probe frames flag
+	autoAccept := flag.Float64("auto-accept", 0.8, "Share of probe matches above which the detected AniList ID is accepted without confirmation.")              // Define the auto accept flag
+	aniListMap := flag.String("anilist-map", "", "Path to a file mapping glob patterns or subfolders to AniList IDs (optional).")                               // Define the AniList map flag
+	cutBorders := flag.Bool("cut-borders", false, "Let trace.moe cut black borders from the frames before searching.")                                          // Define the cut borders flag
+	uploadMode := flag.String("upload-mode", "raw", "How frames are submitted to the search API: raw, multipart or url.")                                       // Define the upload mode flag
+	frameServerAddr := flag.String("frame-server-addr", "127.0.0.1:0", "Listen address of the local frame server used by the url upload mode.")                 // Define the frame server address flag
+	frameServerURL := flag.String("frame-server-url", "", "Public base URL of the local frame server, if the search API reaches it under a different address.") // Define the frame server URL flag
+	recursive := flag.Bool("recursive", false, "Include MKV files in subfolders of the input folder.")                                                          // Define the recursive flag
+
+	// Naming
+	specialsFile := flag.String("specials", "", "Path to a table mapping AniList IDs to S00 special episode numbers (optional).")             // Define the specials table flag
+	seasons := flag.Bool("seasons", false, "Detect season numbers through AniList relations and name files as Series.SxxEyy.")                // Define the seasons flag
+	aniListAPI := flag.String("anilist-api", "https://graphql.anilist.co", "AniList GraphQL API endpoint.")                                   // Define the AniList API endpoint flag
+	enrich := flag.Bool("enrich", false, "Enrich matches with AniList metadata such as format, year, season and episode titles.")             // Define the enrich flag
+	aniListCache := flag.String("anilist-cache", "", "Directory for cached AniList responses (default: anilist in the cache directory).")     // Define the AniList cache flag
+	episodeScheme := flag.String("episode-scheme", "source", "Episode numbering used for the new file names: source, seasonal or absolute.")  // Define the episode scheme flag
+	episodeMap := flag.String("episode-map", "", "Path to the episode mapping file (default: episode-mappings.json in the cache directory).") // Define the episode map flag
+
+	// Search backend
+	apiKey := flag.String("api-key", "", "API key for trace.moe, sent as x-trace-key header (optional).")                                       // Define the API key flag
+	backendURL := flag.String("backend-url", "", "Base URL of a self-hosted trace.moe instance, e.g. http://localhost:3311 (overrides --api).") // Define the backend URL flag
+
+	// Fingerprint index
+	fingerprints := flag.Bool("fingerprints", false, "Match frames against the local fingerprint index before searching and add confidently identified videos to it.")           // Define the fingerprints flag
+	fingerprintIndex := flag.String("fingerprint-index", "", "Path to the fingerprint index (default: fingerprints.json in the cache directory).")                               // Define the fingerprint index flag
+	fingerprintDistance := flag.Int("fingerprint-distance", fingerprint.DefaultMaxDistance, "Maximum number of differing hash bits for a frame to match the fingerprint index.") // Define the fingerprint distance flag
+
+	// Match acceptance
+	minSimilarity := flag.Float64("min-similarity", 0, fmt.Sprintf("Minimum similarity of a search result to be accepted as match (0 - accept any similarity, %.2f recommended).", identifier.RecommendedMinSimilarity)) // Define the minimum similarity flag
+	adult := flag.String("adult", "include", "How adult titles are treated: include, exclude or only.")                                                                                                                  // Define the adult filter flag
+
+	// Naming template
+	nameTemplate := flag.String("name-template", "", "Go text/template for the new file names, may contain / to move files into folders (default: classic Title.Exx naming).") // Define the name template flag
+	sanitize := flag.String("sanitize", "", "Sanitisation rules for titles in file names: strict, safe or none (default: strict, or the rules of the preset).")                // Define the sanitize flag
+
+	// Media server presets
+	preset := flag.String("preset", "", "Naming preset generating folders and file names: plex, jellyfin, kodi or scene (optional).")                             // Define the preset flag
+	idTags := flag.Bool("id-tags", false, "Add AniList ID tags like [anilistid-12345] to the show folders of the jellyfin preset or .IDTag of custom templates.") // Define the ID tags flag
+	outputFolder := flag.String("output", "", "Folder the new names are relative to, e.g. a media library (default: folder of each file).")                       // Define the output folder flag
+
+	// Rename policy
+	rename := flag.String("rename", "ask", "Which files are renamed: never, ask, auto or auto-if-confident.")                                  // Define the rename policy flag
+	renameConfidence := flag.Float64("rename-confidence", 0.90, "Minimum confidence for files to be renamed with --rename auto-if-confident.") // Define the rename confidence flag
+
+	// Dry run
+	dryRun := flag.Bool("dry-run", false, "Write a rename plan instead of renaming, to be carried out later with FumoFinder apply.")                   // Define the dry run flag
+	planFile := flag.String("plan", "rename-plan.json", "Path of the rename plan written by --dry-run, as CSV if it ends in .csv and JSON otherwise.") // Define the plan file flag
+
+	// Rename journal
+	journal := flag.String("journal", "", "Path to the rename journal used by FumoFinder undo (default: rename-journal.jsonl in the cache directory).") // Define the journal flag
+
+	// Name collisions
+	onCollision := flag.String("on-collision", "skip", "What happens when files would get the same name: skip, suffix, keep-best or quarantine.")                   // Define the collision policy flag
+	quarantine := flag.String("quarantine", "", "Folder receiving duplicates with --on-collision quarantine or keep-best (default: duplicates next to each file).") // Define the quarantine folder flag
+
+	// Output mode
+	mode := flag.String("mode", "rename", "How files get their new names: rename, move, hardlink, symlink or copy.")                            // Define the output mode flag
+	libraryRoot := flag.String("library-root", "", "Library folder receiving the renamed files, required by --mode move (overrides --output).") // Define the library root flag
+
+	// Container tags
+	writeTags := flag.Bool("write-tags", false, "Write title, show, episode, season and AniList ID tags into renamed MKV and MP4 files.") // Define the write tags flag
+
+	// NFO files
+	nfo := flag.Bool("nfo", false, "Write Kodi/Jellyfin episodedetails NFO files next to renamed videos and tvshow.nfo files in their show folders.") // Define the NFO flag
 	flag.Parse()
 
 	if *inputFolder == "" {
@@ -41,15 +172,69 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		InputFolder: *inputFolder,
-		FfmpegPath:  *ffmpegPath,
-		FfprobePath: *ffprobePath,
-		NumFrames:   *numFrames,
-		// apikey:        *apikey,
-		ApiEndpoint:   *apiEndpoint,
-		AniListID:     *aniListID,
-		Threshold:     *threshold,
-		NoCleanup:     *noCleanup,
-		ProxyFilePath: *proxyFile,
+		InputFolder:     *inputFolder,
+		FfmpegPath:      *ffmpegPath,
+		FfprobePath:     *ffprobePath,
+		NumFrames:       *numFrames,
+		ApiEndpoint:     *apiEndpoint,
+		AniListID:       *aniListID,
+		Threshold:       *threshold,
+		NoCleanup:       *noCleanup,
+		ProxyFilePath:   *proxyFile,
+		Adaptive:        *adaptive,
+		MinFrames:       *minFrames,
+		MaxFrames:       *maxFrames,
+		Confidence:      *confidence,
+		TrustFilename:   *trustFilename,
+		AutoAniList:     *autoAniList,
+		ProbeFiles:      *probeFiles,
+		ProbeFrames:     *probeFrames,
+		AutoAccept:      *autoAccept,
+		AniListMap:      *aniListMap,
+		Recursive:       *recursive,
+		CutBorders:      *cutBorders,
+		UploadMode:      *uploadMode,
+		FrameServerAddr: *frameServerAddr,
+		FrameServerURL:  *frameServerURL,
+		SpecialsFile:    *specialsFile,
+		Seasons:         *seasons,
+		AniListAPI:      *aniListAPI,
+		Enrich:          *enrich,
+		AniListCache:    *aniListCache,
+		EpisodeScheme:   *episodeScheme,
+		EpisodeMap:      *episodeMap,
+		APIKey:          *apiKey,
+		BackendURL:      *backendURL,
+
+		Fingerprints:        *fingerprints,
+		FingerprintIndex:    *fingerprintIndex,
+		FingerprintDistance: *fingerprintDistance,
+
+		MinSimilarity: *minSimilarity,
+		Adult:         *adult,
+
+		NameTemplate: *nameTemplate,
+		Sanitize:     *sanitize,
+		Preset:       *preset,
+		IDTags:       *idTags,
+		OutputFolder: *outputFolder,
+
+		Rename:           *rename,
+		RenameConfidence: *renameConfidence,
+
+		DryRun:   *dryRun,
+		PlanFile: *planFile,
+
+		Journal: *journal,
+
+		OnCollision: *onCollision,
+		Quarantine:  *quarantine,
+
+		Mode:        *mode,
+		LibraryRoot: *libraryRoot,
+
+		WriteTags: *writeTags,
+
+		NFO: *nfo,
 	}
 }
@@ -8,8 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,6 +20,18 @@ type FrameExtractor struct {
 	ffmpegPath  string
 	ffprobePath string
 	numFrames   int
+	recursive   bool                    // Whether MKV files in subfolders are included
+	videos      map[string]*videoSource // Videos seen during extraction, keyed by path relative to the input folder
+	frames      []string                // All frames extracted so far, including additional ones
+	mu          sync.Mutex              // Mutex to guard access to videos and frames
+}
+
+// videoSource keeps track of a video and the timestamps already sampled from it
+type videoSource struct {
+	path       string    // Path to the video file
+	outputDir  string    // Directory the frames of this video are written to
+	duration   float64   // Duration of the video in seconds
+	timestamps []float64 // Timestamps that have already been sampled
 }
 
 // NewFrameExtractor creates a new FrameExtractor
@@ -26,9 +40,15 @@ func NewFrameExtractor(ffmpegPath string, ffprobePath string, numFrames int) *Fr
 		ffmpegPath:  ffmpegPath,
 		ffprobePath: ffprobePath,
 		numFrames:   numFrames,
+		videos:      make(map[string]*videoSource),
 	}
 }
 
+// SetRecursive includes MKV files in subfolders of the input folder
+func (fe *FrameExtractor) SetRecursive(recursive bool) {
+	fe.recursive = recursive
+}
+
 // ExtractFrames extracts frames at specific intervals from the videos
 func (fe *FrameExtractor) ExtractFrames(inputFolder string) ([]string, error) {
 	var extractedFrames []string
@@ -49,7 +69,7 @@ func (fe *FrameExtractor) ExtractFrames(inputFolder string) ([]string, error) {
 	}
 
 	// Get a list of all MKV files in the input folder; return an error if none are found
-	files, err := filepath.Glob(filepath.Join(inputFolder, "*.mkv"))
+	files, err := fe.findVideoFiles(inputFolder)
 	if err != nil || len(files) == 0 {
 		return nil, errors.New("no MKV files found in the input folder")
 	}
@@ -59,8 +79,14 @@ func (fe *FrameExtractor) ExtractFrames(inputFolder string) ([]string, error) {
 
 	for index, file := range files {
 		// Display a simple loading indicator
-		fmt.Printf("Processing file %d of %d: %s\n", index+1, totalFiles, filepath.Base(file))
-		outputDir := filepath.Join("frames", filepath.Base(file))
+		// Videos in subfolders keep their relative path so names cannot collide
+		videoName, err := filepath.Rel(inputFolder, file)
+		if err != nil {
+			videoName = filepath.Base(file)
+		}
+
+		fmt.Printf("Processing file %d of %d: %s\n", index+1, totalFiles, videoName)
+		outputDir := filepath.Join("frames", videoName)
 		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
 			log.Printf("Failed to create directory for frames: %v", err)
 			continue
@@ -73,23 +99,20 @@ func (fe *FrameExtractor) ExtractFrames(inputFolder string) ([]string, error) {
 			continue
 		}
 
+		// Remember the video so additional frames can be requested later on
+		source := &videoSource{path: file, outputDir: outputDir, duration: duration}
+		fe.mu.Lock()
+		fe.videos[videoName] = source
+		fe.mu.Unlock()
+
 		// Generate timestamps over the duration
 		timestamps := generateTimestamps(duration, fe.numFrames)
 
 		// Extract frames at specific timestamps
 		for i, ts := range timestamps {
-			// Convert timestamp to HH-MM-SS format for filenames
-			timeFormatted := formatTimestamp(ts)
-			outputFrame := filepath.Join(outputDir, fmt.Sprintf("frame_%04d_timestamp_%s.jpg", i+1, timeFormatted))
-
-			// old command for extracting frames way too slow but with better quality - useless tho
-			//cmd := exec.Command(fe.ffmpegPath, "-i", file, "-vf", fmt.Sprintf("select='gte(t,%s)'", ts), "-vsync", "vfr", "-frames:v", "1", "-q:v", "2", outputFrame)
-
-			// new much faster command but with a little bit of quality loss - fine for our purposes
-			cmd := exec.Command(fe.ffmpegPath, "-ss", ts, "-i", file, "-frames:v", "1", "-q:v", "2", outputFrame)
-
-			if output, err := cmd.CombinedOutput(); err != nil {
-				log.Printf("Failed to extract frame at %s from %s: %v\nFFmpeg Output:\n%s", ts, file, err, string(output))
+			outputFrame, err := fe.extractFrame(source, i+1, ts)
+			if err != nil {
+				log.Print(err)
 				continue
 			}
 
@@ -108,6 +131,86 @@ func (fe *FrameExtractor) ExtractFrames(inputFolder string) ([]string, error) {
 	return extractedFrames, nil
 }
 
+// ExtractAdditionalFrames extracts up to count more frames from the largest unsampled regions of a video
+func (fe *FrameExtractor) ExtractAdditionalFrames(videoName string, count int) ([]string, error) {
+	fe.mu.Lock()
+	source, ok := fe.videos[videoName]
+	fe.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("video was not part of the extraction: %s", videoName)
+	}
+
+	var extractedFrames []string
+	for _, ts := range pickUnsampledTimestamps(source.duration, source.timestamps, count) {
+		outputFrame, err := fe.extractFrame(source, len(source.timestamps)+1, fmt.Sprintf("%.2f", ts))
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		extractedFrames = append(extractedFrames, outputFrame)
+	}
+
+	if len(extractedFrames) == 0 {
+		return nil, fmt.Errorf("no additional frames could be extracted from %s", videoName)
+	}
+
+	return extractedFrames, nil
+}
+
+// ExtractedFrames returns every frame extracted so far, including additional frames
+func (fe *FrameExtractor) ExtractedFrames() []string {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+
+	return append([]string(nil), fe.frames...)
+}
+
+// extractFrame extracts a single frame at the given timestamp and records it as sampled
+func (fe *FrameExtractor) extractFrame(source *videoSource, index int, ts string) (string, error) {
+	// Convert timestamp to HH-MM-SS format for filenames
+	timeFormatted := formatTimestamp(ts)
+	outputFrame := filepath.Join(source.outputDir, fmt.Sprintf("frame_%04d_timestamp_%s.jpg", index, timeFormatted))
+
+	// old command for extracting frames way too slow but with better quality - useless tho
+	//cmd := exec.Command(fe.ffmpegPath, "-i", file, "-vf", fmt.Sprintf("select='gte(t,%s)'", ts), "-vsync", "vfr", "-frames:v", "1", "-q:v", "2", outputFrame)
+
+	// new much faster command but with a little bit of quality loss - fine for our purposes
+	cmd := exec.Command(fe.ffmpegPath, "-ss", ts, "-i", source.path, "-frames:v", "1", "-q:v", "2", outputFrame)
+
+	// The timestamp counts as sampled even if extraction fails, so it is not picked again
+	sec, _ := strconv.ParseFloat(ts, 64)
+	source.timestamps = append(source.timestamps, sec)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to extract frame at %s from %s: %v\nFFmpeg Output:\n%s", ts, source.path, err, string(output))
+	}
+
+	fe.mu.Lock()
+	fe.frames = append(fe.frames, outputFrame)
+	fe.mu.Unlock()
+
+	return outputFrame, nil
+}
+
+// findVideoFiles lists the MKV files in the input folder, including subfolders in recursive mode
+func (fe *FrameExtractor) findVideoFiles(inputFolder string) ([]string, error) {
+	if !fe.recursive {
+		return filepath.Glob(filepath.Join(inputFolder, "*.mkv"))
+	}
+
+	var files []string
+	err := filepath.WalkDir(inputFolder, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".mkv") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
 // Helper function to format timestamps
 func formatTimestamp(seconds string) string {
 	sec, _ := strconv.ParseFloat(seconds, 64)
@@ -147,3 +250,37 @@ func generateTimestamps(duration float64, numFrames int) []string {
 
 	return timestamps
 }
+
+// pickUnsampledTimestamps picks timestamps in the middle of the largest gaps between already sampled timestamps
+func pickUnsampledTimestamps(duration float64, sampled []float64, count int) []float64 {
+	// The first 10 seconds are skipped just like for the initial frames
+	points := []float64{10.0, duration}
+	for _, ts := range sampled {
+		if ts > 10.0 && ts < duration {
+			points = append(points, ts)
+		}
+	}
+	sort.Float64s(points)
+
+	var picked []float64
+	for len(picked) < count {
+		// Find the largest gap between two neighbouring points
+		gapIndex, gapSize := -1, 0.0
+		for i := 1; i < len(points); i++ {
+			if size := points[i] - points[i-1]; size > gapSize {
+				gapIndex, gapSize = i, size
+			}
+		}
+
+		// Stop once the remaining gaps are too small to yield a distinct frame
+		if gapIndex < 0 || gapSize < 2.0 {
+			break
+		}
+
+		ts := points[gapIndex-1] + gapSize/2
+		picked = append(picked, ts)
+		points = append(points[:gapIndex], append([]float64{ts}, points[gapIndex:]...)...)
+	}
+
+	return picked
+}
@@ -0,0 +1,114 @@
+// internal/fingerprint/hash.go
+package fingerprint
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // Register the JPEG decoder for the extracted frames
+	_ "image/png"  // Register the PNG decoder
+	"math/bits"
+	"os"
+	"strconv"
+)
+
+// Hash is a 64-bit perceptual difference hash of a frame
+type Hash uint64
+
+// String returns the hash as 16 hex digits
+func (h Hash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// ParseHash parses a hash written by String
+func ParseHash(value string) (Hash, error) {
+	parsed, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fingerprint hash %q: %v", value, err)
+	}
+	return Hash(parsed), nil
+}
+
+// MarshalText stores the hash as hex string in JSON
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText reads a hash stored as hex string
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Distance returns the number of differing bits between two hashes (0 - identical, 64 - inverted)
+func Distance(a, b Hash) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// HashFile decodes an image file and returns its perceptual hash
+func HashFile(path string) (Hash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open frame: %v", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode frame %s: %v", path, err)
+	}
+	return HashImage(img), nil
+}
+
+// HashImage computes the difference hash of an image: the image is scaled down to 9x8 grayscale cells
+// and every bit tells whether a cell is brighter than its right neighbour. Re-encodes, resolution changes
+// and small color shifts of the same scene keep the hash within a few bits.
+func HashImage(img image.Image) Hash {
+	const width, height = 9, 8
+	var cells [height][width]float64
+
+	bounds := img.Bounds()
+	for y := 0; y < height; y++ {
+		top := bounds.Min.Y + y*bounds.Dy()/height
+		bottom := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, top+1)
+		for x := 0; x < width; x++ {
+			left := bounds.Min.X + x*bounds.Dx()/width
+			right := max(bounds.Min.X+(x+1)*bounds.Dx()/width, left+1)
+			cells[y][x] = averageLuminance(img, left, top, right, bottom)
+		}
+	}
+
+	var hash Hash
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuminance returns the average luminance of a rectangle, sampling at most 16x16 pixels
+func averageLuminance(img image.Image, left, top, right, bottom int) float64 {
+	stepX := max((right-left)/16, 1)
+	stepY := max((bottom-top)/16, 1)
+
+	var sum float64
+	var count int
+	for y := top; y < bottom; y += stepY {
+		for x := left; x < right; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
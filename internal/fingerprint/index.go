@@ -0,0 +1,239 @@
+// internal/fingerprint/index.go
+package fingerprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for Title
+)
+
+// DefaultMaxDistance is the number of differing hash bits up to which two frames are considered the same scene
+const DefaultMaxDistance = 6
+
+// Entry is a fingerprinted frame of an identified video
+type Entry struct {
+	Hash      Hash        `json:"hash"`
+	AniListID int         `json:"anilist_id"`
+	Title     string      `json:"title"`              // Display title, shown when inspecting the index
+	Titles    model.Title `json:"titles"`             // Romaji, English and Native titles (empty for frames indexed from file names)
+	IsAdult   bool        `json:"is_adult,omitempty"` // Whether the show is an adult title
+	Episode   string      `json:"episode"`
+	Timestamp float64     `json:"timestamp"` // Position of the frame in the video in seconds
+	Video     string      `json:"video"`     // Name of the video the frame was taken from
+	AddedAt   time.Time   `json:"added_at"`
+}
+
+// Show summarizes the entries of an AniList ID
+type Show struct {
+	AniListID int
+	Title     string
+	Episodes  []string
+	Frames    int
+}
+
+// Index stores fingerprinted frames on disk
+type Index struct {
+	path    string
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// DefaultPath returns the path of the fingerprint index in the FumoFinder cache directory
+func DefaultPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(".", ".fumofinder-cache", "fingerprints.json")
+	}
+	return filepath.Join(cacheDir, "fumofinder", "fingerprints.json")
+}
+
+// Load reads the index from path. A missing file results in an empty index.
+func Load(path string) (*Index, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	index := &Index{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprint index: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &index.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse fingerprint index %s: %v", path, err)
+	}
+	return index, nil
+}
+
+// Path returns the file the index is stored in
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// Save writes the index to its file atomically through a temporary file, so a crash never leaves a truncated index
+func (idx *Index) Save() error {
+	idx.mu.RLock()
+	data, err := json.MarshalIndent(idx.entries, "", "  ")
+	idx.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode fingerprint index: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return fmt.Errorf("failed to create fingerprint index folder: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), "fingerprints-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), idx.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write fingerprint index: %v", err)
+	}
+	return nil
+}
+
+// Len returns the number of fingerprinted frames
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// Add stores entries, skipping frames already stored with the same hash for the same episode.
+// It returns the number of added entries.
+func (idx *Index) Add(entries ...Entry) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	added := 0
+	for _, entry := range entries {
+		if idx.containsLocked(entry) {
+			continue
+		}
+		if entry.AddedAt.IsZero() {
+			entry.AddedAt = time.Now()
+		}
+		idx.entries = append(idx.entries, entry)
+		added++
+	}
+	return added
+}
+
+// containsLocked reports whether the same frame is already stored; the caller must hold the lock
+func (idx *Index) containsLocked(entry Entry) bool {
+	for _, existing := range idx.entries {
+		if existing.Hash == entry.Hash && existing.AniListID == entry.AniListID && existing.Episode == entry.Episode {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup finds the closest stored frame within maxDistance, restricted to an AniList ID (0 - all).
+// Frames that match several episodes equally well, such as openings and endings, are ambiguous and not returned.
+func (idx *Index) Lookup(hash Hash, aniListID int, maxDistance int) (Entry, int, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var best Entry
+	bestDistance := maxDistance + 1
+	ambiguous := false
+	for _, entry := range idx.entries {
+		if aniListID != 0 && entry.AniListID != aniListID {
+			continue
+		}
+
+		distance := Distance(hash, entry.Hash)
+		if distance < bestDistance {
+			best, bestDistance, ambiguous = entry, distance, false
+		} else if distance == bestDistance && (entry.AniListID != best.AniListID || entry.Episode != best.Episode) {
+			ambiguous = true
+		}
+	}
+
+	if bestDistance > maxDistance || ambiguous {
+		return Entry{}, 0, false
+	}
+	return best, bestDistance, true
+}
+
+// Prune removes every entry the predicate returns true for and returns the number of removed entries
+func (idx *Index) Prune(remove func(Entry) bool) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	kept := idx.entries[:0]
+	for _, entry := range idx.entries {
+		if !remove(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	removed := len(idx.entries) - len(kept)
+	idx.entries = kept
+	return removed
+}
+
+// Shows summarizes the index per AniList ID, ordered by AniList ID
+func (idx *Index) Shows() []Show {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	shows := make(map[int]*Show)
+	episodes := make(map[int]map[string]bool)
+	for _, entry := range idx.entries {
+		show, ok := shows[entry.AniListID]
+		if !ok {
+			show = &Show{AniListID: entry.AniListID, Title: entry.Title}
+			shows[entry.AniListID] = show
+			episodes[entry.AniListID] = make(map[string]bool)
+		}
+		show.Frames++
+		if !episodes[entry.AniListID][entry.Episode] {
+			episodes[entry.AniListID][entry.Episode] = true
+			show.Episodes = append(show.Episodes, entry.Episode)
+		}
+	}
+
+	var result []Show
+	for _, show := range shows {
+		sort.Slice(show.Episodes, func(i, j int) bool { return episodeLess(show.Episodes[i], show.Episodes[j]) })
+		result = append(result, *show)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AniListID < result[j].AniListID })
+	return result
+}
+
+// episodeLess orders episode numbers numerically where possible
+func episodeLess(a, b string) bool {
+	var numberA, numberB float64
+	_, errA := fmt.Sscan(a, &numberA)
+	_, errB := fmt.Sscan(b, &numberB)
+	if errA == nil && errB == nil {
+		return numberA < numberB
+	}
+	return a < b
+}
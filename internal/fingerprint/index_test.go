@@ -0,0 +1,69 @@
+package fingerprint
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexLookup(t *testing.T) {
+	idx := &Index{}
+	idx.Add(
+		Entry{Hash: 0x0000_0000_0000_00ff, AniListID: 1, Title: "Show", Episode: "1"},
+		Entry{Hash: 0xff00_0000_0000_0000, AniListID: 1, Title: "Show", Episode: "2"},
+		Entry{Hash: 0xff00_0000_0000_0000, AniListID: 1, Title: "Show", Episode: "2", Video: "same frame of another release"},
+		Entry{Hash: 0x00ff_00ff_0000_0000, AniListID: 1, Title: "Show", Episode: "3"}, // Opening shared with episode 4
+		Entry{Hash: 0x00ff_00ff_0000_0000, AniListID: 1, Title: "Show", Episode: "4"},
+		Entry{Hash: 0x0f0f_0f0f_0000_0000, AniListID: 2, Title: "Other", Episode: "1"},
+	)
+
+	tests := []struct {
+		name        string
+		hash        Hash
+		aniListID   int
+		maxDistance int
+		found       bool
+		episode     string
+		distance    int
+	}{
+		{"exact match", 0x0000_0000_0000_00ff, 0, DefaultMaxDistance, true, "1", 0},
+		{"close match", 0x0000_0000_0000_00fc, 0, DefaultMaxDistance, true, "1", 2},
+		{"too far", 0x0000_0000_0000_00ff ^ 0xff, 0, DefaultMaxDistance, false, "", 0},
+		{"duplicate entries of one episode", 0xff00_0000_0000_0000, 0, DefaultMaxDistance, true, "2", 0},
+		{"scene shared by episodes", 0x00ff_00ff_0000_0000, 0, DefaultMaxDistance, false, "", 0},
+		{"restricted to other show", 0x0000_0000_0000_00ff, 2, DefaultMaxDistance, false, "", 0},
+		{"restricted to matching show", 0x0f0f_0f0f_0000_0001, 2, DefaultMaxDistance, true, "1", 1},
+		{"zero distance only", 0x0000_0000_0000_00fe, 0, 0, false, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, distance, found := idx.Lookup(tt.hash, tt.aniListID, tt.maxDistance)
+			if found != tt.found || entry.Episode != tt.episode || distance != tt.distance {
+				t.Errorf("Lookup = episode %q, distance %d, found %t, want episode %q, distance %d, found %t",
+					entry.Episode, distance, found, tt.episode, tt.distance, tt.found)
+			}
+		})
+	}
+}
+
+func TestIndexSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "fingerprints.json")
+	idx, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added := idx.Add(Entry{Hash: 0xabcdef, AniListID: 1, Episode: "1"}, Entry{Hash: 0xabcdef, AniListID: 1, Episode: "1"}); added != 1 {
+		t.Errorf("added %d entries, want the duplicate skipped", added)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, _, found := loaded.Lookup(0xabcdef, 1, 0); !found || entry.Episode != "1" {
+		t.Errorf("saved entry not found after loading, got %+v", entry)
+	}
+}
@@ -0,0 +1,170 @@
+// internal/foldermap/resolver.go
+package foldermap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MarkerFileName is the name of the marker file holding the AniList ID of a show folder
+const MarkerFileName = ".fumofinder"
+
+// Rule maps a glob pattern or subfolder to an AniList ID
+type Rule struct {
+	Pattern   string // Glob pattern or subfolder, relative to the input folder and using forward slashes
+	AniListID int    // AniList ID used as filter for matching videos
+}
+
+// Resolver determines the AniList ID filter of each video from marker files, mapping rules and a default ID
+type Resolver struct {
+	inputFolder string         // Path to the folder containing the videos
+	defaultID   int            // AniList ID used when neither a marker nor a rule applies (0 - filter disabled)
+	rules       []Rule         // Mapping rules, the first matching rule wins
+	markers     map[string]int // Cache of marker file lookups, keyed by directory
+	mu          sync.Mutex     // Mutex to guard access to the marker cache
+}
+
+// NewResolver creates a new Resolver for the input folder with the given default AniList ID
+func NewResolver(inputFolder string, defaultID int) *Resolver {
+	return &Resolver{
+		inputFolder: inputFolder,
+		defaultID:   defaultID,
+		markers:     make(map[string]int),
+	}
+}
+
+// LoadMappingFile loads mapping rules from a file. Each line maps a glob pattern or subfolder to an AniList ID:
+//
+//	Frieren/ = 154587
+//	*Mob Psycho* = 21507
+//
+// Empty lines and lines starting with # are ignored.
+func (r *Resolver) LoadMappingFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open AniList mapping file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split on the last = so patterns may contain the character themselves
+		index := strings.LastIndex(line, "=")
+		if index < 0 {
+			return fmt.Errorf("invalid AniList mapping entry on line %d: %s", lineNumber, line)
+		}
+		aniListID, err := strconv.Atoi(strings.TrimSpace(line[index+1:]))
+		if err != nil {
+			return fmt.Errorf("invalid AniList ID on line %d: %v", lineNumber, err)
+		}
+
+		pattern := strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(line[:index])), "/")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern on line %d: %v", lineNumber, err)
+		}
+		r.rules = append(r.rules, Rule{Pattern: pattern, AniListID: aniListID})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read AniList mapping file: %v", err)
+	}
+	return nil
+}
+
+// Rules returns the loaded mapping rules
+func (r *Resolver) Rules() []Rule {
+	return r.rules
+}
+
+// AniListIDFor returns the AniList ID filter for a video, given by its path relative to the input folder.
+// The nearest marker file wins over the mapping rules, which win over the default ID.
+func (r *Resolver) AniListIDFor(videoName string) int {
+	if aniListID := r.markerFor(filepath.Dir(videoName)); aniListID != 0 {
+		return aniListID
+	}
+
+	video := filepath.ToSlash(videoName)
+	for _, rule := range r.rules {
+		if matchesRule(rule.Pattern, video) {
+			return rule.AniListID
+		}
+	}
+
+	return r.defaultID
+}
+
+// matchesRule reports whether a video path matches a glob pattern or lies inside the given subfolder
+func matchesRule(pattern, video string) bool {
+	if matched, _ := path.Match(pattern, video); matched {
+		return true
+	}
+	if matched, _ := path.Match(pattern, path.Base(video)); matched {
+		return true
+	}
+	return strings.HasPrefix(video, pattern+"/")
+}
+
+// markerFor looks for a marker file in the directory and its parents up to the input folder
+func (r *Resolver) markerFor(relativeDir string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if aniListID, ok := r.markers[relativeDir]; ok {
+		return aniListID
+	}
+
+	aniListID, err := readMarker(filepath.Join(r.inputFolder, relativeDir, MarkerFileName))
+	if err != nil {
+		fmt.Printf("⚠️ Ignoring invalid marker file in %s: %v\n", filepath.Join(r.inputFolder, relativeDir), err)
+	}
+
+	// Fall back to the parent directory until the input folder itself was checked
+	if aniListID == 0 && relativeDir != "." && relativeDir != "" {
+		r.mu.Unlock()
+		aniListID = r.markerFor(filepath.Dir(relativeDir))
+		r.mu.Lock()
+	}
+
+	r.markers[relativeDir] = aniListID
+	return aniListID
+}
+
+// readMarker reads the AniList ID from a marker file; a missing file yields 0.
+// The file holds the ID on its own or as "anilist=<id>".
+func readMarker(markerPath string) (int, error) {
+	data, err := os.ReadFile(markerPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			if !strings.EqualFold(strings.TrimSpace(key), "anilist") {
+				continue
+			}
+			line = strings.TrimSpace(value)
+		}
+		return strconv.Atoi(line)
+	}
+	return 0, nil
+}
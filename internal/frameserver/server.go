@@ -0,0 +1,93 @@
+// internal/frameserver/server.go
+package frameserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Server serves extracted frames over HTTP so a search backend can fetch them by URL
+type Server struct {
+	root     string       // Directory the frames are served from
+	baseURL  *url.URL     // Public base URL the backend uses to reach the server
+	listener net.Listener // Listener the server accepts connections on
+	server   *http.Server // Underlying HTTP server
+}
+
+// Start serves the frames in root on the listen address. If publicURL is empty, the listener address is used,
+// which only works when the search backend runs on the same machine or network.
+func Start(root, listenAddr, publicURL string) (*Server, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start frame server: %v", err)
+	}
+
+	if publicURL == "" {
+		publicURL = "http://" + listener.Addr().String()
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(publicURL, "/") + "/")
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("invalid frame server URL %s: %v", publicURL, err)
+	}
+
+	fs := &Server{
+		root:     root,
+		baseURL:  baseURL,
+		listener: listener,
+		server: &http.Server{
+			Handler:           frameHandler(root),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	go func() {
+		if err := fs.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("⚠️ Frame server stopped: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("ℹ️ Serving frames from %s at %s\n", root, baseURL)
+	return fs, nil
+}
+
+// frameHandler serves the files below root, answering requests for folders with 404 instead of listing the extracted frames
+func frameHandler(root string) http.Handler {
+	files := http.FileServer(http.Dir(root))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		if info, err := os.Stat(name); err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// URLFor returns the public URL of a frame stored below the root directory
+func (fs *Server) URLFor(framePath string) (string, error) {
+	relative, err := filepath.Rel(fs.root, framePath)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return "", fmt.Errorf("frame %s is not served by the frame server", framePath)
+	}
+
+	// Escape every path segment, video names often contain spaces and brackets
+	segments := strings.Split(filepath.ToSlash(relative), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fs.baseURL.String() + strings.Join(segments, "/"), nil
+}
+
+// Close stops the frame server
+func (fs *Server) Close() error {
+	return fs.server.Close()
+}
@@ -0,0 +1,85 @@
+package identifier
+
+import (
+	"fmt"
+
+	"github.com/WhereIsF1/FumoFinder/internal/anilist" // Import the anilist package for metadata enrichment
+)
+
+// EnrichMatches fills in AniList metadata that trace.moe does not return, such as format, year, season and episode titles
+func (ei *EpisodeIdentifier) EnrichMatches(client anilist.Client) {
+	titles := ei.applyMedia(client, enrichMatch)
+	fmt.Printf("ℹ️ Enriched matches with AniList metadata for %d titles.\n", titles)
+}
+
+// ClassifyMatches fills in only the format, episode count and year of the matches, which trace.moe does not return either,
+// so movies, OVAs and specials are recognised when AniList is queried for season detection without full enrichment
+func (ei *EpisodeIdentifier) ClassifyMatches(client anilist.Client) {
+	titles := ei.applyMedia(client, classifyMatch)
+	fmt.Printf("ℹ️ Fetched the AniList format of %d titles.\n", titles)
+}
+
+// applyMedia fetches the AniList media of every matched AniList ID once and applies it to the matches.
+// The lock is only held while reading and updating the matches, not during the requests.
+// It returns the number of AniList IDs looked up.
+func (ei *EpisodeIdentifier) applyMedia(client anilist.Client, apply func(*MatchInfo, *anilist.Media)) int {
+	// Collect every AniList ID only once
+	ei.mu.Lock()
+	var ids []int
+	media := make(map[int]*anilist.Media)
+	for _, match := range ei.Matches {
+		if _, seen := media[match.AnilistID]; match.AnilistID != 0 && !seen {
+			media[match.AnilistID] = nil
+			ids = append(ids, match.AnilistID)
+		}
+	}
+	ei.mu.Unlock()
+
+	for _, id := range ids {
+		info, err := client.Media(id)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to fetch AniList metadata for ID %d, movies are only recognised by a missing episode number: %v\n", id, err)
+			continue
+		}
+		media[id] = info
+	}
+
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	for i := range ei.Matches {
+		if info := media[ei.Matches[i].AnilistID]; info != nil {
+			apply(&ei.Matches[i], info)
+		}
+	}
+	return len(ids)
+}
+
+// classifyMatch copies the format, episode count and year of a media into a match
+func classifyMatch(match *MatchInfo, media *anilist.Media) {
+	match.Format = media.Format
+	match.EpisodeCount = media.Episodes
+	match.Year = media.StartDate.Year
+	if match.Year == 0 {
+		match.Year = media.SeasonYear
+	}
+}
+
+// enrichMatch copies the AniList metadata of a media into a match, keeping values trace.moe already provided
+func enrichMatch(match *MatchInfo, media *anilist.Media) {
+	classifyMatch(match, media)
+	match.Season = media.Season
+	match.SeasonYear = media.SeasonYear
+	match.EpisodeTitle = media.EpisodeTitle(match.Episode.String())
+
+	if match.MalID == 0 {
+		match.MalID = media.IDMal
+	}
+	if match.TitleNative == "" && match.TitleRomaji == "" && match.TitleEnglish == "" {
+		match.TitleNative = media.Title.Native
+		match.TitleRomaji = media.Title.Romaji
+		match.TitleEnglish = media.Title.English
+	}
+	if len(match.Synonyms) == 0 {
+		match.Synonyms = media.Synonyms
+	}
+}
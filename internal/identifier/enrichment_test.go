@@ -0,0 +1,60 @@
+package identifier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/anilist"
+	"github.com/WhereIsF1/FumoFinder/internal/model"
+)
+
+// fakeAniList serves media from a map and records the requested IDs
+type fakeAniList struct {
+	media     map[int]*anilist.Media
+	requested []int
+	onMedia   func() // Called on every request
+}
+
+func (f *fakeAniList) Media(id int) (*anilist.Media, error) {
+	f.requested = append(f.requested, id)
+	if f.onMedia != nil {
+		f.onMedia()
+	}
+	if media, ok := f.media[id]; ok {
+		return media, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestClassifyMatches(t *testing.T) {
+	ei := &EpisodeIdentifier{Matches: []MatchInfo{
+		{AnilistID: 1, Episode: model.EpisodeNumber{Raw: "1"}},
+		{AnilistID: 1, Episode: model.EpisodeNumber{Raw: "1"}},
+		{AnilistID: 2, Episode: model.EpisodeNumber{Raw: "3"}},
+		{AnilistID: 3},
+		{AnilistID: 0},
+	}}
+	client := &fakeAniList{media: map[int]*anilist.Media{
+		1: {ID: 1, Format: "MOVIE", Episodes: 1},
+		2: {ID: 2, Format: "OVA", Episodes: 6},
+	}}
+	client.onMedia = func() {
+		if !ei.mu.TryLock() {
+			t.Error("matches locked while AniList is queried")
+			return
+		}
+		ei.mu.Unlock()
+	}
+
+	ei.ClassifyMatches(client)
+
+	if len(client.requested) != 3 {
+		t.Errorf("requested %v, want every AniList ID once", client.requested)
+	}
+	want := []MediaKind{Movie, Movie, Special, Movie, Movie}
+	for i, match := range ei.Matches {
+		if kind := ClassifyMedia(match.Format, match.EpisodeCount, match.Episode.String()); kind != want[i] {
+			t.Errorf("match %d classified as %s, want %s", i, kind, want[i])
+		}
+	}
+}
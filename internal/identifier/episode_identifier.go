@@ -1,19 +1,19 @@
 package identifier
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for TraceMoeResponse
-	"github.com/WhereIsF1/FumoFinder/internal/proxy" // Import proxy package to access ProxyDetails
+	"github.com/WhereIsF1/FumoFinder/internal/backend"     // Import the backend package for SearchBackend
+	"github.com/WhereIsF1/FumoFinder/internal/model"       // Import the model package for EpisodeNumber
+	"github.com/WhereIsF1/FumoFinder/internal/proxy"       // Import proxy package to access ProxyDetails
+	"github.com/WhereIsF1/FumoFinder/internal/releasename" // Import the releasename package for file name hints
 )
 
 // Define a struct for saving match information
@@ -25,6 +25,12 @@ type MatchInfo struct {
 	TitleEnglish string              `json:"title_english"`
 	Synonyms     []string            `json:"synonyms"`
 	IsAdult      bool                `json:"is_adult"`
+	Format       string              `json:"format"`
+	EpisodeCount int                 `json:"episode_count"`
+	Year         int                 `json:"year"`
+	Season       string              `json:"season"`
+	SeasonYear   int                 `json:"season_year"`
+	EpisodeTitle string              `json:"episode_title"`
 	Episode      model.EpisodeNumber `json:"episode"`
 	Similarity   float64             `json:"similarity"`
 	Timestamp    float64             `json:"timestamp"`
@@ -38,26 +44,64 @@ type MatchInfo struct {
 	ImageURL     string              `json:"image_url"`
 }
 
+// DisplayTitle returns the title of a match, prioritizing English and falling back to Romaji or Native
+func (m MatchInfo) DisplayTitle() string {
+	return model.Title{Native: m.TitleNative, Romaji: m.TitleRomaji, English: m.TitleEnglish}.Preferred()
+}
+
+// ConfidenceWarningLevel is the share of frames agreeing on an episode below which a result is considered unreliable
+const ConfidenceWarningLevel = 0.90
+
+// FrameSupplier provides additional frames for a video when the adaptive frame budget asks for more samples
+type FrameSupplier interface {
+	ExtractAdditionalFrames(videoName string, count int) ([]string, error)
+}
+
+// AdaptiveBudget configures how many frames are sent per video when the adaptive frame budget is enabled
+type AdaptiveBudget struct {
+	MinFrames        int           // Minimum number of matched frames before a video can be considered settled
+	MaxFrames        int           // Maximum number of frames sent per video, including additional frames
+	ConfidenceTarget float64       // Share of votes the leading episode needs before no more frames are sent
+	Supplier         FrameSupplier // Source of additional frames for ambiguous videos
+}
+
+// AniListResolver provides the AniList ID filter of each video
+type AniListResolver interface {
+	AniListIDFor(videoName string) int
+}
+
 // EpisodeIdentifier handles identifying episodes using trace.moe
 type EpisodeIdentifier struct {
-	apiEndpoint    string                       // API endpoint for trace.moe
+	backend        backend.SearchBackend        // Search backend the frames are sent to
 	aniListID      int                          // AniList ID to filter results
+	aniListIDs     AniListResolver              // Per-video AniList ID filter, nil to use aniListID for every video
 	Matches        []MatchInfo                  // Slice to store match information
 	httpClients    map[*http.Client]string      // Map of HTTP clients with proxy URLs
 	clientLocks    map[*http.Client]*sync.Mutex // Map to guard access to the clients
 	frameCounts    map[string]int               // Map to track frames processed by each proxy
 	failCounts     map[string]int               // Map to track failed attempts
 	brokenProxies  map[string]bool              // Map to track broken proxies
+	exhausted      map[string]bool              // Map to track proxies whose search quota is used up
 	mu             sync.Mutex                   // Mutex to guard access to the maps
 	done           chan struct{}                // Channel to signal when processing is complete
 	channelClosed  atomic.Bool                  // Atomic flag to track if the channel is closed
 	sendMutex      sync.Mutex                   // Mutex to guard access to the SafeSend function
 	wg             sync.WaitGroup               // WaitGroup to wait for all workers to finish
 	completionChan chan struct{}                // Channel to signal completion of identification process
+	adaptive       *AdaptiveBudget              // Adaptive frame budget, nil if every frame is sent
+	framesQueued   map[string]int               // Map to track frames queued for each video
+	framesSkipped  int                          // Number of frames skipped because their video was already settled
+	filenameSample int                          // Matched frames agreeing with the file name needed to skip the rest, 0 to disable
+	filter         MatchFilter                  // Similarity floor and adult filter applied to search results
+	rejections     map[string][]Rejection       // Rejected search results of each frame without a match
+	retryDelay     time.Duration                // First delay before retrying a rate limited search, doubled on every retry
 }
 
+// maxSearchRetries is the number of times a rate limited or failing search is retried before the frame is dropped
+const maxSearchRetries = 3
+
 // NewEpisodeIdentifier creates a new EpisodeIdentifier with optional proxy support
-func NewEpisodeIdentifier(apiEndpoint string, aniListID int, proxies []proxy.ProxyDetails) *EpisodeIdentifier {
+func NewEpisodeIdentifier(searchBackend backend.SearchBackend, aniListID int, proxies []proxy.ProxyDetails) *EpisodeIdentifier {
 	clients := make(map[*http.Client]string)
 	clientLocks := make(map[*http.Client]*sync.Mutex)
 	frameCounts := make(map[string]int)
@@ -88,7 +132,7 @@ func NewEpisodeIdentifier(apiEndpoint string, aniListID int, proxies []proxy.Pro
 	}
 
 	return &EpisodeIdentifier{
-		apiEndpoint:    apiEndpoint,
+		backend:        searchBackend,
 		aniListID:      aniListID,
 		Matches:        []MatchInfo{},
 		httpClients:    clients,
@@ -96,18 +140,73 @@ func NewEpisodeIdentifier(apiEndpoint string, aniListID int, proxies []proxy.Pro
 		frameCounts:    frameCounts,
 		failCounts:     failCounts,
 		brokenProxies:  brokenProxies,
+		exhausted:      make(map[string]bool),
 		done:           make(chan struct{}),
 		completionChan: make(chan struct{}), // Initialize completion channel
+		framesQueued:   make(map[string]int),
+		rejections:     make(map[string][]Rejection),
+		retryDelay:     2 * time.Second,
+	}
+}
+
+// EnableAdaptiveBudget stops sending frames for settled videos and requests more frames for ambiguous ones
+func (ei *EpisodeIdentifier) EnableAdaptiveBudget(budget AdaptiveBudget) {
+	ei.adaptive = &budget
+}
+
+// SetAniListResolver applies a per-video AniList ID filter instead of the single aniListID
+func (ei *EpisodeIdentifier) SetAniListResolver(resolver AniListResolver) {
+	ei.aniListIDs = resolver
+}
+
+// aniListIDFor returns the AniList ID filter of a video (0 - filter disabled)
+func (ei *EpisodeIdentifier) aniListIDFor(video string) int {
+	if ei.aniListIDs != nil {
+		return ei.aniListIDs.AniListIDFor(video)
 	}
+	return ei.aniListID
+}
+
+// TrustFilenameHints stops sending frames for a video once sampleSize matched frames all agree with the episode in its file name
+func (ei *EpisodeIdentifier) TrustFilenameHints(sampleSize int) {
+	ei.filenameSample = sampleSize
 }
 
 // IdentifyEpisodes processes frames concurrently using multiple proxies with dynamic allocation
 func (ei *EpisodeIdentifier) IdentifyEpisodes(frames []string, threshold float64) {
+	ei.processRound(frames, threshold)
+
+	// Keep sampling ambiguous videos until they are settled or their frame budget is used up
+	if ei.adaptive != nil {
+		for round := 2; ; round++ {
+			additionalFrames := ei.requestAdditionalFrames()
+			if len(additionalFrames) == 0 {
+				break
+			}
+
+			fmt.Printf("\n🔁 Adaptive round %d: sending %d additional frames for ambiguous videos...\n", round, len(additionalFrames))
+			ei.processRound(additionalFrames, threshold)
+		}
+	}
+
+	// Display summary of frames processed by each proxy
+	ei.displayFrameProcessingSummary()
+
+	fmt.Println("Episode identification process completed.")
+	close(ei.completionChan) // Signal completion when the function exits
+}
+
+// processRound sends a batch of frames through all proxy clients and waits until the batch is processed
+func (ei *EpisodeIdentifier) processRound(frames []string, threshold float64) {
+	ei.done = make(chan struct{})
+	ei.channelClosed.Store(false)
+
 	frameChan := make(chan string, len(frames))
 
 	// Load all frames into the shared channel
 	for _, frame := range frames {
 		frameChan <- frame
+		ei.framesQueued[videoNameFromFrame(frame)]++
 	}
 
 	// Start processing frames dynamically with each proxy client concurrently
@@ -121,12 +220,116 @@ func (ei *EpisodeIdentifier) IdentifyEpisodes(frames []string, threshold float64
 
 	// Safely close the channel after all processing is done
 	ei.CloseFramesChannel(frameChan)
+}
 
-	// Display summary of frames processed by each proxy
-	ei.displayFrameProcessingSummary()
+// requestAdditionalFrames asks the frame supplier for more frames of every video that is still ambiguous
+func (ei *EpisodeIdentifier) requestAdditionalFrames() []string {
+	// Request a few frames at a time so clear videos do not use up the whole budget
+	batchSize := ei.adaptive.MinFrames / 2
+	if batchSize < 1 {
+		batchSize = 1
+	}
 
-	fmt.Println("Episode identification process completed.")
-	close(ei.completionChan) // Signal completion when the function exits
+	var additionalFrames []string
+	for video, queued := range ei.framesQueued {
+		ei.mu.Lock()
+		votes := ei.videoConfidence(video)
+		ei.mu.Unlock()
+
+		// Only sample more when the video is not settled and the votes are split or weak
+		if ei.isSettled(video, votes) {
+			continue
+		}
+		if votes.matched >= ei.adaptive.MinFrames && votes.confidence >= ConfidenceWarningLevel && !votes.tied {
+			continue
+		}
+
+		remaining := ei.adaptive.MaxFrames - queued
+		if remaining <= 0 {
+			continue
+		}
+
+		frames, err := ei.adaptive.Supplier.ExtractAdditionalFrames(video, min(batchSize, remaining))
+		if err != nil {
+			fmt.Printf("⚠️ Could not extract additional frames for %s: %v\n", video, err)
+			// Do not ask for this video again
+			ei.framesQueued[video] = ei.adaptive.MaxFrames
+			continue
+		}
+		additionalFrames = append(additionalFrames, frames...)
+	}
+
+	return additionalFrames
+}
+
+// videoVotes summarises the episode votes of a single video
+type videoVotes struct {
+	matched    int     // Number of matched frames
+	leading    string  // Episode with the most votes
+	confidence float64 // Vote share of the leading episode
+	tied       bool    // Whether another episode has as many votes as the leading one
+}
+
+// videoConfidence counts the episode votes of a video; the caller must hold ei.mu
+func (ei *EpisodeIdentifier) videoConfidence(video string) videoVotes {
+	episodeCount := make(map[string]int)
+	var votes videoVotes
+	for _, match := range ei.Matches {
+		if match.VideoName == video {
+			episodeCount[match.Episode.String()]++
+			votes.matched++
+		}
+	}
+	if votes.matched == 0 {
+		return votes
+	}
+
+	leading := 0
+	for episode, count := range episodeCount {
+		if count > leading {
+			leading, votes.leading, votes.tied = count, episode, false
+		} else if count == leading {
+			votes.tied = true
+		}
+	}
+	votes.confidence = float64(leading) / float64(votes.matched)
+
+	return votes
+}
+
+// isSettled reports whether a video has enough agreeing frames to stop sending more of them
+func (ei *EpisodeIdentifier) isSettled(video string, votes videoVotes) bool {
+	if ei.adaptive != nil && votes.matched >= ei.adaptive.MinFrames && votes.confidence >= ei.adaptive.ConfidenceTarget && !votes.tied {
+		return true
+	}
+
+	// A small sample that fully agrees with the episode in the file name is enough
+	if ei.filenameSample > 0 && votes.matched >= ei.filenameSample && votes.confidence == 1 {
+		hint := releasename.Parse(video).Episode
+		return hint != "" && hint == votes.leading
+	}
+
+	return false
+}
+
+// ConfidentMatches returns the matches of the leading episode of every video whose vote share reaches minConfidence
+func (ei *EpisodeIdentifier) ConfidentMatches(minConfidence float64) []MatchInfo {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	votes := make(map[string]videoVotes)
+	var confident []MatchInfo
+	for _, match := range ei.Matches {
+		video, counted := votes[match.VideoName]
+		if !counted {
+			video = ei.videoConfidence(match.VideoName)
+			votes[match.VideoName] = video
+		}
+		if video.confidence >= minConfidence && !video.tied && match.Episode.String() == video.leading {
+			confident = append(confident, match)
+		}
+	}
+	return confident
 }
 
 // SafeSend safely sends a frame back to the channel without panic
@@ -178,6 +381,21 @@ func (ei *EpisodeIdentifier) processFrames(client *http.Client, proxyURL string,
 				fmt.Printf("⚠️ Proxy %s is marked as broken, terminating worker.\n", proxyURL)
 				return // Exit to prevent further processing
 			}
+			if ei.exhausted[proxyURL] {
+				ei.mu.Unlock()
+				ei.SafeSend(frames, frame)
+				return
+			}
+
+			// Skip the frame if its video is already settled by the adaptive frame budget or its file name
+			if ei.adaptive != nil || ei.filenameSample > 0 {
+				video := videoNameFromFrame(frame)
+				if ei.isSettled(video, ei.videoConfidence(video)) {
+					ei.framesSkipped++
+					ei.mu.Unlock()
+					continue
+				}
+			}
 			ei.mu.Unlock()
 
 			// Process the frame
@@ -185,6 +403,21 @@ func (ei *EpisodeIdentifier) processFrames(client *http.Client, proxyURL string,
 			info, similarity, err := ei.IdentifyEpisode(frame, threshold, client, proxyURL)
 			ei.clientLocks[client].Unlock()
 
+			// Refusals of the search API are not failures of the proxy or connection
+			var apiErr *backend.APIError
+			if errors.As(err, &apiErr) {
+				if apiErr.QuotaExhausted() {
+					ei.mu.Lock()
+					ei.exhausted[proxyURL] = true
+					ei.mu.Unlock()
+					fmt.Printf("❌ The search quota of %s is used up, no more frames are sent through it: %v\n", proxyURL, err)
+					ei.SafeSend(frames, frame) // Leave the frame to connections with quota left
+					return
+				}
+				fmt.Printf("⚠️ Dropping frame %s, the search API refused it: %v\n", frame, err)
+				continue
+			}
+
 			if err != nil {
 				if proxyURL != "No Proxy (Direct Connection)" {
 					ei.handleProxyFailure(proxyURL, frames, frame) // Handle the broken proxy
@@ -265,7 +498,28 @@ func (ei *EpisodeIdentifier) getClientByProxyURL(proxyURL string) *http.Client {
 	return nil
 }
 
-// IdentifyEpisode identifies the episode by sending a frame to trace.moe using a specific client
+// search sends a frame to the search backend, backing off and retrying while the API is rate limited or failing.
+// The delay requested with Retry-After is honoured, otherwise the delay doubles on every retry.
+func (ei *EpisodeIdentifier) search(client *http.Client, imagePath string, options backend.SearchOptions) (*model.TraceMoeResponse, error) {
+	delay := ei.retryDelay
+	for retry := 1; ; retry++ {
+		result, err := ei.backend.Search(client, imagePath, options)
+		var apiErr *backend.APIError
+		if err == nil || !errors.As(err, &apiErr) || !apiErr.Temporary() || retry > maxSearchRetries {
+			return result, err
+		}
+
+		wait := delay
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		fmt.Printf("⏳ %v, retrying %s in %s (%d/%d).\n", err, filepath.Base(imagePath), wait, retry, maxSearchRetries)
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// IdentifyEpisode identifies the episode by sending a frame to the search backend using a specific client
 func (ei *EpisodeIdentifier) IdentifyEpisode(imagePath string, threshold float64, client *http.Client, proxyURL string) (string, float64, error) {
 	// Check if the proxy is flagged as broken, if so, skip using it
 	ei.mu.Lock()
@@ -275,51 +529,51 @@ func (ei *EpisodeIdentifier) IdentifyEpisode(imagePath string, threshold float64
 	}
 	ei.mu.Unlock()
 
-	file, err := os.Open(imagePath)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to open frame: %v", err)
-	}
-	defer file.Close()
+	// Extract video filename and determine the AniList ID filter of this video
+	videoFilename := videoNameFromFrame(imagePath)
+	aniListID := ei.aniListIDFor(videoFilename)
 
-	var buf bytes.Buffer
-	if _, err = buf.ReadFrom(file); err != nil {
-		return "", 0, fmt.Errorf("failed to read frame: %v", err)
-	}
-
-	// Ensure requests go through the provided client
-	req, err := http.NewRequest("POST", ei.apiEndpoint, &buf)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to create request to trace.moe: %v", err)
-	}
-	req.Header.Set("Content-Type", "image/jpeg")
-
-	resp, err := client.Do(req)
+	// Send the frame through the provided client
+	result, err := ei.search(client, imagePath, backend.SearchOptions{AniListID: aniListID})
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to send frame to trace.moe: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var result model.TraceMoeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", 0, fmt.Errorf("failed to parse trace.moe response: %v", err)
+		return "", 0, err
 	}
 
 	// Extract timestamp from the frame filename in seconds
 	timestampSec := extractTimestampInSeconds(imagePath)
 	var reasons []string         // To collect reasons for mismatches
+	var rejections []Rejection   // To record why each result was filtered
 	foundPotentialMatch := false // Flag to indicate potential matches
 
-	// Extract video filename
-	videoFilename := filepath.Base(filepath.Dir(imagePath))
+	reject := func(match model.TraceMoeResult, reason RejectionReason) {
+		rejections = append(rejections, Rejection{
+			VideoName:  videoFilename,
+			FrameName:  filepath.Base(imagePath),
+			Reason:     reason,
+			AnilistID:  match.Anilist.ID,
+			Episode:    match.Episode.String(),
+			Similarity: match.Similarity * 100,
+		})
+	}
 
 	// Iterate through results to find matches based on AniList ID
 	for _, match := range result.Result {
 		// Check AniList ID match
-		if ei.aniListID != 0 && ei.aniListID != match.Anilist.ID {
+		if aniListID != 0 && aniListID != match.Anilist.ID {
 			// Collect mismatch reason and skip to next result
 			reasons = append(reasons, fmt.Sprintf(
 				"❌ AniList ID Mismatch:\n   - Expected: %d\n   - Found: %d\n   - Video: %s\n   - Frame: %s",
-				ei.aniListID, match.Anilist.ID, videoFilename, filepath.Base(imagePath)))
+				aniListID, match.Anilist.ID, videoFilename, filepath.Base(imagePath)))
+			reject(match, RejectAniListID)
+			continue
+		}
+
+		// Check the similarity floor and the adult filter
+		if reason := ei.filter.check(match); reason != "" {
+			reasons = append(reasons, fmt.Sprintf(
+				"❌ Rejected (%s):\n   - Similarity: %.2f%% (minimum %.2f%%)\n   - Adult: %t\n   - Video: %s\n   - Frame: %s",
+				reason, match.Similarity*100, ei.filter.MinSimilarity*100, match.Anilist.IsAdult, videoFilename, filepath.Base(imagePath)))
+			reject(match, reason)
 			continue
 		}
 
@@ -357,15 +611,10 @@ func (ei *EpisodeIdentifier) IdentifyEpisode(imagePath string, threshold float64
 			ei.mu.Lock()
 			ei.Matches = append(ei.Matches, matchInfo)
 			ei.mu.Unlock()
+			ei.recordRejections(imagePath, nil)
 
 			// Check for English title; if empty, fall back to Romaji or Native title
-			title := match.Anilist.Title.English
-			if title == "" {
-				title = match.Anilist.Title.Romaji
-				if title == "" {
-					title = match.Anilist.Title.Native
-				}
-			}
+			title := match.Anilist.Title.Preferred()
 
 			// Display match info, including proxy used
 			info := fmt.Sprintf(
@@ -389,11 +638,17 @@ func (ei *EpisodeIdentifier) IdentifyEpisode(imagePath string, threshold float64
 			reasons = append(reasons, fmt.Sprintf(
 				"❌ Timestamp Mismatch:\n   - Timestamp: %.2f\n   - Expected Range: %.2f to %.2f\n   - Threshold: ±%.2f seconds\n   - Video: %s\n   - Frame: %s",
 				timestampSec, match.From, match.To, threshold, videoFilename, filepath.Base(imagePath)))
+			reject(match, RejectTimestamp)
 		}
 	}
 
+	if len(result.Result) == 0 {
+		rejections = append(rejections, Rejection{VideoName: videoFilename, FrameName: filepath.Base(imagePath), Reason: RejectNoResults})
+	}
+	ei.recordRejections(imagePath, rejections)
+
 	// Log only the most relevant reason if no match is found after checking all results
-	if foundPotentialMatch && len(reasons) > 0 {
+	if (foundPotentialMatch || len(rejections) > 0) && len(reasons) > 0 {
 		fmt.Printf(
 			"\n❌ Failed to Identify Episode for Frame:\n   - Video: %s\n   - Frame: %s\n"+
 				"🔍 Reason: %s\n"+
@@ -409,6 +664,20 @@ func (ei *EpisodeIdentifier) IdentifyEpisode(imagePath string, threshold float64
 	return "", 0, nil
 }
 
+// FrameSource returns the video a frame was extracted from and the position of the frame in the video in seconds
+func FrameSource(imagePath string) (string, float64) {
+	return videoNameFromFrame(imagePath), extractTimestampInSeconds(imagePath)
+}
+
+// videoNameFromFrame returns the name of the video a frame was extracted from, relative to the input folder
+func videoNameFromFrame(imagePath string) string {
+	// Frames are stored as frames/<video path>/frame_xxxx_timestamp_hh-mm-ss.jpg
+	if videoName, err := filepath.Rel("frames", filepath.Dir(imagePath)); err == nil && !strings.HasPrefix(videoName, "..") {
+		return videoName
+	}
+	return filepath.Base(filepath.Dir(imagePath))
+}
+
 // ExtractTimestampInSeconds extracts the timestamp from the frame filename in seconds
 func extractTimestampInSeconds(imagePath string) float64 {
 	filename := filepath.Base(imagePath)
@@ -435,5 +704,8 @@ func (ei *EpisodeIdentifier) displayFrameProcessingSummary() {
 	for proxy, count := range ei.frameCounts {
 		fmt.Printf("   - %s processed %d frames\n", proxy, count)
 	}
+	if ei.adaptive != nil || ei.filenameSample > 0 {
+		fmt.Printf("   - %d frames skipped because their video was already settled\n", ei.framesSkipped)
+	}
 	fmt.Println(strings.Repeat("=", 50))
 }
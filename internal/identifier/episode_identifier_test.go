@@ -0,0 +1,229 @@
+package identifier
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend"
+	"github.com/WhereIsF1/FumoFinder/internal/extractor"
+	"github.com/WhereIsF1/FumoFinder/internal/proxy"
+	"github.com/WhereIsF1/FumoFinder/internal/tracemoetest"
+)
+
+// testThreshold is the timestamp tolerance used by the tests in seconds
+const testThreshold = 5
+
+// chdir changes into dir for the duration of the test, since frames are stored relative to the working directory
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+// extractFrames creates the videos in a temporary folder and extracts numFrames frames of each with the FFmpeg shim
+func extractFrames(t *testing.T, numFrames int, videos ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	ffmpegPath, ffprobePath, err := tracemoetest.WriteFFmpegShim(dir, 1440)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracemoetest.WriteVideos("videos", videos...); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := extractor.NewFrameExtractor(ffmpegPath, ffprobePath, numFrames).ExtractFrames("videos")
+	if err != nil {
+		t.Fatalf("ExtractFrames: %v", err)
+	}
+	return frames
+}
+
+// newTestIdentifier creates an identifier searching the fake server with a short retry delay
+func newTestIdentifier(server *tracemoetest.Server, proxies []proxy.ProxyDetails) *EpisodeIdentifier {
+	ei := NewEpisodeIdentifier(server.Backend(backend.TraceMoeConfig{}), 0, proxies)
+	ei.retryDelay = time.Millisecond
+	return ei
+}
+
+func TestIdentifyEpisodesPlainMatch(t *testing.T) {
+	frames := extractFrames(t, 3, "[Group] Show - 05 [1080p].mkv", "[Group] Show - 06 [1080p].mkv")
+
+	server := tracemoetest.NewServer()
+	defer server.Close()
+	server.OnFrame("Show - 05", tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95)))
+	server.OnFrame("Show - 06", tracemoetest.Found(tracemoetest.Match(1001, "Show", 6, tracemoetest.AtFrameTime, 0.93)))
+
+	ei := newTestIdentifier(server, nil)
+	ei.IdentifyEpisodes(frames, testThreshold)
+
+	if len(ei.Matches) != len(frames) {
+		t.Fatalf("got %d matches, want %d", len(ei.Matches), len(frames))
+	}
+	want := map[string]string{"[Group] Show - 05 [1080p].mkv": "5", "[Group] Show - 06 [1080p].mkv": "6"}
+	for _, match := range ei.Matches {
+		if episode, ok := want[match.VideoName]; !ok || match.Episode.String() != episode {
+			t.Errorf("video %q matched episode %s, want %q", match.VideoName, match.Episode, episode)
+		}
+		if match.AnilistID != 1001 || match.DisplayTitle() != "Show" {
+			t.Errorf("match of %s has AniList ID %d and title %q", match.VideoName, match.AnilistID, match.DisplayTitle())
+		}
+		if match.Timestamp < match.From-testThreshold || match.Timestamp > match.To+testThreshold {
+			t.Errorf("frame at %.2f matched the scene %.2f to %.2f", match.Timestamp, match.From, match.To)
+		}
+	}
+	if searches := server.Searches(); searches != len(frames) {
+		t.Errorf("sent %d searches, want %d", searches, len(frames))
+	}
+}
+
+func TestIdentifyEpisodesResponses(t *testing.T) {
+	found := tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95))
+
+	tests := []struct {
+		name      string
+		responses []tracemoetest.Response
+		timeout   time.Duration // Client timeout, 0 to keep the default
+		matches   int
+		searches  int
+	}{
+		{"match", []tracemoetest.Response{found}, 0, 1, 1},
+		{"rate limited", []tracemoetest.Response{tracemoetest.TooManyRequests(), tracemoetest.TooManyRequests(), found}, 0, 1, 3},
+		{"rate limit persists", []tracemoetest.Response{tracemoetest.TooManyRequests()}, 0, 0, maxSearchRetries + 1},
+		{"server error", []tracemoetest.Response{tracemoetest.ServerError(), found}, 0, 1, 2},
+		{"malformed", []tracemoetest.Response{tracemoetest.Malformed()}, 0, 0, 4},
+		{"malformed once", []tracemoetest.Response{tracemoetest.Malformed(), found}, 0, 1, 2},
+		{"slow", []tracemoetest.Response{tracemoetest.Slow(500*time.Millisecond, found)}, 100 * time.Millisecond, 0, 4},
+		{"quota depleted", []tracemoetest.Response{tracemoetest.QuotaDepleted()}, 0, 0, 1},
+		{"no results", []tracemoetest.Response{tracemoetest.Found()}, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := extractFrames(t, 1, "Show - 05.mkv")
+
+			server := tracemoetest.NewServer()
+			defer server.Close()
+			server.OnFrame("Show - 05", tt.responses...)
+
+			ei := newTestIdentifier(server, nil)
+			if tt.timeout > 0 {
+				for client := range ei.httpClients {
+					client.Timeout = tt.timeout
+				}
+			}
+			ei.IdentifyEpisodes(frames, testThreshold)
+
+			if len(ei.Matches) != tt.matches {
+				t.Errorf("got %d matches, want %d", len(ei.Matches), tt.matches)
+			}
+			if searches := server.Searches(); searches != tt.searches {
+				t.Errorf("sent %d searches, want %d", searches, tt.searches)
+			}
+		})
+	}
+}
+
+func TestIdentifyEpisodesHonoursRetryAfter(t *testing.T) {
+	frames := extractFrames(t, 1, "Show - 05.mkv")
+
+	server := tracemoetest.NewServer()
+	defer server.Close()
+	server.OnFrame("Show - 05",
+		tracemoetest.RetryAfter(1, tracemoetest.TooManyRequests()),
+		tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95)))
+
+	ei := newTestIdentifier(server, nil)
+	start := time.Now()
+	ei.IdentifyEpisodes(frames, testThreshold)
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least the 1s requested by Retry-After", elapsed)
+	}
+	if len(ei.Matches) != 1 {
+		t.Errorf("got %d matches, want 1", len(ei.Matches))
+	}
+}
+
+func TestIdentifyEpisodesStopsAtUsedUpQuota(t *testing.T) {
+	frames := extractFrames(t, 3, "Show - 05.mkv")
+
+	server := tracemoetest.NewServer()
+	defer server.Close()
+	server.SetQuota(2, 0)
+	server.SetFallback(tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95)))
+
+	ei := newTestIdentifier(server, nil)
+	ei.IdentifyEpisodes(frames, testThreshold)
+
+	if len(ei.Matches) != 2 {
+		t.Errorf("got %d matches, want the 2 searches allowed by the quota", len(ei.Matches))
+	}
+	if searches := server.Searches(); searches != 3 {
+		t.Errorf("sent %d searches, want no more after the quota was refused", searches)
+	}
+	if !ei.exhausted["No Proxy (Direct Connection)"] {
+		t.Error("direct connection not marked as exhausted")
+	}
+}
+
+func TestIdentifyEpisodesRefusalsDoNotBreakProxies(t *testing.T) {
+	frames := extractFrames(t, 1, "Show - 05.mkv")
+
+	server := tracemoetest.NewServer()
+	defer server.Close()
+	server.OnFrame("Show - 05",
+		tracemoetest.TooManyRequests(),
+		tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95)))
+
+	forwarder := tracemoetest.NewProxy()
+	defer forwarder.Close()
+	proxyURL, err := url.Parse(forwarder.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ei := newTestIdentifier(server, []proxy.ProxyDetails{{URL: proxyURL}})
+	ei.IdentifyEpisodes(frames, testThreshold)
+
+	if len(ei.Matches) != 1 || ei.Matches[0].ProxyUsed != proxyURL.String() {
+		t.Fatalf("got matches %+v, want one match through %s", ei.Matches, proxyURL)
+	}
+	if ei.brokenProxies[proxyURL.String()] || ei.failCounts[proxyURL.String()] != 0 {
+		t.Error("rate limit of the search API counted as a proxy failure")
+	}
+	if forwarder.Requests() != 2 {
+		t.Errorf("proxy forwarded %d requests, want 2", forwarder.Requests())
+	}
+}
+
+func TestFrameSource(t *testing.T) {
+	tests := []struct {
+		frame     string
+		video     string
+		timestamp float64
+	}{
+		{filepath.Join("frames", "Show - 05.mkv", "frame_0001_timestamp_00-00-10.jpg"), "Show - 05.mkv", 10},
+		{filepath.Join("frames", "Season 1", "Show - 05.mkv", "frame_0002_timestamp_00-08-00.jpg"), filepath.Join("Season 1", "Show - 05.mkv"), 480},
+		{filepath.Join("frames", "Movie.mkv", "frame_0003_timestamp_01-30-05.jpg"), "Movie.mkv", 5405},
+		{filepath.Join("elsewhere", "Show - 05.mkv", "frame_0001.jpg"), "Show - 05.mkv", 0},
+	}
+
+	for _, tt := range tests {
+		video, timestamp := FrameSource(tt.frame)
+		if video != tt.video || timestamp != tt.timestamp {
+			t.Errorf("FrameSource(%q) = %q, %v, want %q, %v", tt.frame, video, timestamp, tt.video, tt.timestamp)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package identifier
+
+import (
+	"sort"
+	"strconv"
+)
+
+// LayoutKind describes how the episodes matched within a single video are laid out
+type LayoutKind int
+
+const (
+	SingleEpisode LayoutKind = iota // The video contains a single episode
+	MultiEpisode                    // The video contains several consecutive episodes back to back
+	Unreliable                      // The frames match many episodes at random
+)
+
+// EpisodeRun is a contiguous part of a video in which all frames matched the same episode
+type EpisodeRun struct {
+	Episode string  // Episode number matched by the frames of this run
+	Start   float64 // Timestamp of the first frame in the run
+	End     float64 // Timestamp of the last frame in the run
+	Frames  int     // Number of frames in the run
+}
+
+// EpisodeLayout is the result of analysing how the matched episodes of a video are laid out
+type EpisodeLayout struct {
+	Kind LayoutKind   // Kind of layout detected
+	Runs []EpisodeRun // Contiguous episode runs ordered by timestamp
+}
+
+// AnalyzeEpisodeLayout orders the matches of a single video by timestamp and detects multi-episode files and unreliable results
+func AnalyzeEpisodeLayout(matches []MatchInfo) EpisodeLayout {
+	if len(matches) == 0 {
+		return EpisodeLayout{Kind: SingleEpisode}
+	}
+
+	// Order the matches by their position in the video
+	sorted := append([]MatchInfo(nil), matches...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	runs := mergeNoiseRuns(collectRuns(sorted))
+	if len(runs) == 1 {
+		return EpisodeLayout{Kind: SingleEpisode, Runs: runs}
+	}
+
+	if isConsecutiveRunSequence(runs) {
+		return EpisodeLayout{Kind: MultiEpisode, Runs: runs}
+	}
+
+	// Count the votes of each episode to see if there is any clear majority left
+	episodeCount := make(map[string]int)
+	leading := 0
+	for _, match := range sorted {
+		episodeCount[match.Episode.String()]++
+		leading = max(leading, episodeCount[match.Episode.String()])
+	}
+
+	// Frames spread over many episodes without a majority are not trustworthy
+	if len(episodeCount) >= 3 && float64(leading)/float64(len(sorted)) < 0.5 {
+		return EpisodeLayout{Kind: Unreliable, Runs: runs}
+	}
+
+	return EpisodeLayout{Kind: SingleEpisode, Runs: runs}
+}
+
+// collectRuns groups matches ordered by timestamp into runs of the same episode
+func collectRuns(sorted []MatchInfo) []EpisodeRun {
+	var runs []EpisodeRun
+	for _, match := range sorted {
+		episode := match.Episode.String()
+		if len(runs) > 0 && runs[len(runs)-1].Episode == episode {
+			runs[len(runs)-1].End = match.Timestamp
+			runs[len(runs)-1].Frames++
+			continue
+		}
+		runs = append(runs, EpisodeRun{Episode: episode, Start: match.Timestamp, End: match.Timestamp, Frames: 1})
+	}
+	return runs
+}
+
+// mergeNoiseRuns drops single stray frames that interrupt a run of the same episode
+func mergeNoiseRuns(runs []EpisodeRun) []EpisodeRun {
+	var merged []EpisodeRun
+	for i := 0; i < len(runs); i++ {
+		// A single frame between two runs of the same episode is treated as noise and counted towards the surrounding run
+		if i > 0 && i+1 < len(runs) && runs[i].Frames == 1 && runs[i-1].Episode == runs[i+1].Episode && len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			last.End = runs[i+1].End
+			last.Frames += runs[i].Frames + runs[i+1].Frames
+			i++
+			continue
+		}
+		merged = append(merged, runs[i])
+	}
+	return merged
+}
+
+// isConsecutiveRunSequence reports whether the runs form a clean sequence of consecutive episodes, each appearing once
+func isConsecutiveRunSequence(runs []EpisodeRun) bool {
+	previous := 0.0
+	for i, run := range runs {
+		// A single frame is not enough evidence for a whole episode
+		if run.Frames < 2 {
+			return false
+		}
+
+		number, err := strconv.ParseFloat(run.Episode, 64)
+		if err != nil {
+			return false
+		}
+		if i > 0 && number != previous+1 {
+			return false
+		}
+		previous = number
+	}
+	return true
+}
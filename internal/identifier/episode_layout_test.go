@@ -0,0 +1,78 @@
+package identifier
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model"
+)
+
+// framesOf creates matches of one video, each episode label taking the next frame 60 seconds later
+func framesOf(episodes ...string) []MatchInfo {
+	matches := make([]MatchInfo, len(episodes))
+	for i, episode := range episodes {
+		number, _ := strconv.ParseFloat(episode, 64)
+		matches[i] = MatchInfo{Episode: model.EpisodeNumber{Number: number, Raw: episode}, Timestamp: float64(i * 60)}
+	}
+	return matches
+}
+
+func TestAnalyzeEpisodeLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		matches  []MatchInfo
+		kind     LayoutKind
+		episodes []string
+	}{
+		{"no matches", nil, SingleEpisode, nil},
+		{"single episode", framesOf("5", "5", "5", "5"), SingleEpisode, []string{"5"}},
+		{"two episodes back to back", framesOf("1", "1", "1", "2", "2", "2"), MultiEpisode, []string{"1", "2"}},
+		{"three episodes back to back", framesOf("3", "3", "4", "4", "5", "5"), MultiEpisode, []string{"3", "4", "5"}},
+		{"stray frame inside a run", framesOf("5", "5", "9", "5", "5"), SingleEpisode, []string{"5"}},
+		{"episodes not consecutive", framesOf("1", "1", "1", "3", "3", "3"), SingleEpisode, []string{"1", "3"}},
+		{"single frame of the next episode", framesOf("1", "1", "1", "1", "2"), SingleEpisode, []string{"1", "2"}},
+		{"random episodes", framesOf("1", "7", "3", "9", "4", "12"), Unreliable, []string{"1", "7", "3", "9", "4", "12"}},
+		{"stray frames merged", framesOf("5", "5", "8", "5", "2", "5"), SingleEpisode, []string{"5"}},
+		{"majority over other runs", framesOf("5", "5", "8", "8", "5", "5", "2"), SingleEpisode, []string{"5", "8", "5", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := AnalyzeEpisodeLayout(tt.matches)
+			if layout.Kind != tt.kind {
+				t.Errorf("kind = %d, want %d", layout.Kind, tt.kind)
+			}
+			var episodes []string
+			for _, run := range layout.Runs {
+				episodes = append(episodes, run.Episode)
+			}
+			if !reflect.DeepEqual(episodes, tt.episodes) {
+				t.Errorf("runs = %v, want %v", episodes, tt.episodes)
+			}
+		})
+	}
+}
+
+func TestAnalyzeEpisodeLayoutCountsMergedNoise(t *testing.T) {
+	layout := AnalyzeEpisodeLayout(framesOf("1", "1", "7", "1", "1", "2", "2", "2", "2", "2"))
+	if layout.Kind != MultiEpisode || len(layout.Runs) != 2 {
+		t.Fatalf("got %+v, want two episode runs", layout)
+	}
+	if first := layout.Runs[0]; first.Frames != 5 || first.Start != 0 || first.End != 240 {
+		t.Errorf("first run = %+v, want all 5 frames from 0 to 240", first)
+	}
+}
+
+func TestAnalyzeEpisodeLayoutOrdersByTimestamp(t *testing.T) {
+	matches := framesOf("1", "1", "2", "2")
+	matches[0], matches[3] = matches[3], matches[0]
+
+	layout := AnalyzeEpisodeLayout(matches)
+	if layout.Kind != MultiEpisode || len(layout.Runs) != 2 {
+		t.Fatalf("got %+v, want two episode runs", layout)
+	}
+	if first := layout.Runs[0]; first.Episode != "1" || first.Start != 0 || first.End != 60 || first.Frames != 2 {
+		t.Errorf("first run = %+v", first)
+	}
+}
@@ -0,0 +1,147 @@
+package identifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for TraceMoeResult
+)
+
+// RecommendedMinSimilarity is the similarity below which trace.moe results are usually wrong.
+// The floor is opt-in, by default every similarity is accepted.
+const RecommendedMinSimilarity = 0.87
+
+// AdultFilter selects how search results of adult titles are treated
+type AdultFilter string
+
+const (
+	AdultInclude AdultFilter = "include" // Accept adult and non-adult titles
+	AdultExclude AdultFilter = "exclude" // Reject adult titles
+	AdultOnly    AdultFilter = "only"    // Reject non-adult titles
+)
+
+// ParseAdultFilter parses an adult filter name
+func ParseAdultFilter(name string) (AdultFilter, error) {
+	switch filter := AdultFilter(strings.ToLower(strings.TrimSpace(name))); filter {
+	case AdultInclude, AdultExclude, AdultOnly:
+		return filter, nil
+	case "":
+		return AdultInclude, nil
+	default:
+		return "", fmt.Errorf("unknown adult filter: %s (expected include, exclude or only)", name)
+	}
+}
+
+// MatchFilter holds the acceptance rules applied to every search result before its timestamp is checked
+type MatchFilter struct {
+	MinSimilarity float64     // Minimum similarity between 0 and 1 (0 - accept any similarity)
+	Adult         AdultFilter // Treatment of adult titles
+}
+
+// RejectionReason tells why a search result was not accepted as match
+type RejectionReason string
+
+const (
+	RejectNoResults  RejectionReason = "no results"
+	RejectAniListID  RejectionReason = "AniList ID mismatch"
+	RejectSimilarity RejectionReason = "low similarity"
+	RejectAdult      RejectionReason = "adult title excluded"
+	RejectNotAdult   RejectionReason = "non-adult title excluded"
+	RejectTimestamp  RejectionReason = "timestamp mismatch"
+)
+
+// Rejection records a search result that was filtered out
+type Rejection struct {
+	VideoName  string
+	FrameName  string
+	Reason     RejectionReason
+	AnilistID  int     // AniList ID of the rejected result (0 for RejectNoResults)
+	Episode    string  // Episode of the rejected result
+	Similarity float64 // Similarity of the rejected result in percent
+}
+
+// check returns the reason a result is rejected by the filter, or an empty reason if it passes
+func (mf MatchFilter) check(result model.TraceMoeResult) RejectionReason {
+	if mf.MinSimilarity > 0 && result.Similarity < mf.MinSimilarity {
+		return RejectSimilarity
+	}
+	if mf.Adult == AdultExclude && result.Anilist.IsAdult {
+		return RejectAdult
+	}
+	if mf.Adult == AdultOnly && !result.Anilist.IsAdult {
+		return RejectNotAdult
+	}
+	return ""
+}
+
+// SetMatchFilter sets the similarity floor and the adult filter applied to search results
+func (ei *EpisodeIdentifier) SetMatchFilter(filter MatchFilter) {
+	ei.filter = filter
+}
+
+// recordRejections replaces the rejections of a frame, so retried frames are not counted twice
+func (ei *EpisodeIdentifier) recordRejections(imagePath string, rejections []Rejection) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	if len(rejections) == 0 {
+		delete(ei.rejections, imagePath)
+		return
+	}
+	ei.rejections[imagePath] = rejections
+}
+
+// Rejections returns the rejected search results of every frame that did not produce a match
+func (ei *EpisodeIdentifier) Rejections() []Rejection {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	var frames []string
+	for frame := range ei.rejections {
+		frames = append(frames, frame)
+	}
+	sort.Strings(frames)
+
+	var rejections []Rejection
+	for _, frame := range frames {
+		rejections = append(rejections, ei.rejections[frame]...)
+	}
+	return rejections
+}
+
+// DisplayRejectionSummary explains for every video without a match why its search results were rejected
+func (ei *EpisodeIdentifier) DisplayRejectionSummary() {
+	ei.mu.Lock()
+	matched := make(map[string]bool)
+	for _, match := range ei.Matches {
+		matched[match.VideoName] = true
+	}
+	ei.mu.Unlock()
+
+	reasons := make(map[string]map[RejectionReason]int)
+	var videos []string
+	for _, rejection := range ei.Rejections() {
+		if matched[rejection.VideoName] {
+			continue
+		}
+		if reasons[rejection.VideoName] == nil {
+			reasons[rejection.VideoName] = make(map[RejectionReason]int)
+			videos = append(videos, rejection.VideoName)
+		}
+		reasons[rejection.VideoName][rejection.Reason]++
+	}
+	if len(videos) == 0 {
+		return
+	}
+
+	fmt.Println("\n📋 Videos without a match:")
+	for _, video := range videos {
+		var parts []string
+		for _, reason := range []RejectionReason{RejectSimilarity, RejectAdult, RejectNotAdult, RejectAniListID, RejectTimestamp, RejectNoResults} {
+			if count := reasons[video][reason]; count > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+			}
+		}
+		fmt.Printf("   - %s: %s\n", video, strings.Join(parts, ", "))
+	}
+}
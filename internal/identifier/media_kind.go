@@ -0,0 +1,46 @@
+package identifier
+
+import "strings"
+
+// MediaKind describes how a matched media should be named
+type MediaKind int
+
+const (
+	Series  MediaKind = iota // Regular episodic series, named Title.Exx
+	Movie                    // Single-episode movie, named Title (Year)
+	Special                  // OVA, ONA or special episode, named Title.S00Exx
+)
+
+// ClassifyMedia recognises single-episode formats from AniList format metadata.
+// When the format is unknown, a missing episode number is taken as a sign of a movie.
+func ClassifyMedia(format string, episodeCount int, episode string) MediaKind {
+	switch strings.ToUpper(format) {
+	case "MOVIE":
+		return Movie
+	case "OVA", "SPECIAL":
+		return Special
+	case "ONA":
+		// ONAs are often regular web series, only single-episode ONAs are treated as specials
+		if episodeCount == 1 {
+			return Special
+		}
+		return Series
+	case "":
+		if episode == "" {
+			return Movie
+		}
+	}
+	return Series
+}
+
+// String returns the name of the media kind as used in naming templates
+func (k MediaKind) String() string {
+	switch k {
+	case Movie:
+		return "movie"
+	case Special:
+		return "special"
+	default:
+		return "series"
+	}
+}
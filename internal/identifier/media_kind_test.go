@@ -0,0 +1,30 @@
+package identifier
+
+import "testing"
+
+func TestClassifyMedia(t *testing.T) {
+	tests := []struct {
+		format       string
+		episodeCount int
+		episode      string
+		want         MediaKind
+	}{
+		{"TV", 12, "5", Series},
+		{"TV_SHORT", 24, "5", Series},
+		{"MOVIE", 1, "", Movie},
+		{"movie", 1, "1", Movie},
+		{"OVA", 2, "1", Special},
+		{"SPECIAL", 6, "3", Special},
+		{"ONA", 1, "", Special},
+		{"ONA", 12, "5", Series},
+		{"", 0, "", Movie},
+		{"", 0, "5", Series},
+		{"MUSIC", 1, "1", Series},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyMedia(tt.format, tt.episodeCount, tt.episode); got != tt.want {
+			t.Errorf("ClassifyMedia(%q, %d, %q) = %s, want %s", tt.format, tt.episodeCount, tt.episode, got, tt.want)
+		}
+	}
+}
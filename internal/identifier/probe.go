@@ -0,0 +1,82 @@
+package identifier
+
+import "sort"
+
+// SelectProbeFrames picks up to framesPerVideo evenly spread frames from up to maxVideos videos
+func SelectProbeFrames(frames []string, maxVideos int, framesPerVideo int) []string {
+	// Group the frames by video, keeping the order in which videos appear
+	var videos []string
+	framesByVideo := make(map[string][]string)
+	for _, frame := range frames {
+		video := videoNameFromFrame(frame)
+		if _, seen := framesByVideo[video]; !seen {
+			videos = append(videos, video)
+		}
+		framesByVideo[video] = append(framesByVideo[video], frame)
+	}
+
+	// Spread the probed videos over the whole folder
+	var probe []string
+	selectedVideos := spreadSelect(videos, maxVideos)
+	for _, video := range selectedVideos {
+		probe = append(probe, spreadSelect(framesByVideo[video], framesPerVideo)...)
+	}
+	return probe
+}
+
+// spreadSelect picks up to count items evenly spread over the slice
+func spreadSelect(items []string, count int) []string {
+	if count <= 0 {
+		return nil
+	}
+	if len(items) <= count {
+		return items
+	}
+
+	selected := make([]string, 0, count)
+	step := float64(len(items)) / float64(count)
+	for i := 0; i < count; i++ {
+		selected = append(selected, items[int(float64(i)*step+step/2)])
+	}
+	return selected
+}
+
+// DominantAniListID returns the AniList ID matched most often, its display title and its share of all matches
+func DominantAniListID(matches []MatchInfo) (int, string, float64) {
+	if len(matches) == 0 {
+		return 0, "", 0
+	}
+
+	counts := make(map[int]int)
+	titles := make(map[int]string)
+	for _, match := range matches {
+		counts[match.AnilistID]++
+		if titles[match.AnilistID] == "" {
+			titles[match.AnilistID] = match.DisplayTitle()
+		}
+	}
+
+	// Sort the IDs so ties are resolved deterministically
+	ids := make([]int, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	dominant := ids[0]
+	for _, id := range ids {
+		if counts[id] > counts[dominant] {
+			dominant = id
+		}
+	}
+
+	return dominant, titles[dominant], float64(counts[dominant]) / float64(len(matches))
+}
+
+// AddMatches adds matches found outside of IdentifyEpisodes, e.g. during a probe run
+func (ei *EpisodeIdentifier) AddMatches(matches []MatchInfo) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+
+	ei.Matches = append(ei.Matches, matches...)
+}
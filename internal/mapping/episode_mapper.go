@@ -0,0 +1,138 @@
+// internal/mapping/episode_mapper.go
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for EpisodeNumber
+)
+
+// Scheme selects the episode numbering used when renaming files
+type Scheme string
+
+const (
+	SchemeSource   Scheme = "source"   // Keep the numbering returned by trace.moe
+	SchemeSeasonal Scheme = "seasonal" // Convert to season/episode pairs
+	SchemeAbsolute Scheme = "absolute" // Convert to absolute episode numbers
+)
+
+// ParseScheme parses an episode numbering scheme name
+func ParseScheme(name string) (Scheme, error) {
+	switch scheme := Scheme(strings.ToLower(strings.TrimSpace(name))); scheme {
+	case SchemeSource, SchemeSeasonal, SchemeAbsolute:
+		return scheme, nil
+	case "":
+		return SchemeSource, nil
+	default:
+		return "", fmt.Errorf("unknown episode scheme: %s (expected source, seasonal or absolute)", name)
+	}
+}
+
+// Entry describes one season of a show and how trace.moe numbers the episodes of its AniList ID
+type Entry struct {
+	AnilistID     int    `json:"anilist_id"`     // AniList ID the entry applies to
+	Season        int    `json:"season"`         // Season number expected by the media server
+	AbsoluteStart int    `json:"absolute_start"` // Absolute number of the first episode of the season
+	Episodes      int    `json:"episodes"`       // Number of episodes in the season
+	Numbering     string `json:"numbering"`      // Numbering trace.moe uses for this AniList ID: "absolute" (default) or "seasonal"
+}
+
+// Episode is an episode number expressed in both numbering schemes
+type Episode struct {
+	Season   int     // Season number
+	Seasonal float64 // Episode number within the season
+	Absolute float64 // Absolute episode number across all seasons
+}
+
+// Mapper converts between absolute and seasonal episode numbers
+type Mapper struct {
+	entries map[int][]Entry // Entries keyed by AniList ID
+}
+
+// DefaultPath returns the location of the mapping dataset in the FumoFinder cache directory
+func DefaultPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "fumofinder", "episode-mappings.json")
+}
+
+// LoadMapper loads a mapping file containing a JSON array of entries
+func LoadMapper(filePath string) (*Mapper, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read episode mapping file: %v", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse episode mapping file: %v", err)
+	}
+
+	mapper := &Mapper{entries: make(map[int][]Entry)}
+	for i, entry := range entries {
+		if entry.AnilistID == 0 || entry.Season < 0 || entry.AbsoluteStart < 1 || entry.Episodes < 1 {
+			return nil, fmt.Errorf("invalid episode mapping entry %d: %+v", i+1, entry)
+		}
+		mapper.entries[entry.AnilistID] = append(mapper.entries[entry.AnilistID], entry)
+	}
+
+	return mapper, nil
+}
+
+// Len returns the number of entries in the mapper
+func (m *Mapper) Len() int {
+	count := 0
+	for _, entries := range m.entries {
+		count += len(entries)
+	}
+	return count
+}
+
+// FromMatch converts the episode number of a trace.moe match into both numbering schemes
+func (m *Mapper) FromMatch(aniListID int, episode model.EpisodeNumber) (Episode, bool) {
+	if episode.Number == 0 {
+		return Episode{}, false
+	}
+
+	for _, entry := range m.entries[aniListID] {
+		if entry.Numbering == "seasonal" {
+			// trace.moe numbers this AniList ID per season
+			if episode.Number < 1 || episode.Number >= float64(entry.Episodes+1) {
+				continue
+			}
+			return Episode{
+				Season:   entry.Season,
+				Seasonal: episode.Number,
+				Absolute: episode.Number + float64(entry.AbsoluteStart-1),
+			}, true
+		}
+
+		// trace.moe numbers this AniList ID across all seasons
+		last := float64(entry.AbsoluteStart + entry.Episodes)
+		if episode.Number >= float64(entry.AbsoluteStart) && episode.Number < last {
+			return Episode{
+				Season:   entry.Season,
+				Seasonal: episode.Number - float64(entry.AbsoluteStart-1),
+				Absolute: episode.Number,
+			}, true
+		}
+	}
+
+	return Episode{}, false
+}
+
+// ToAbsolute converts a season/episode pair of an AniList ID back into an absolute episode number
+func (m *Mapper) ToAbsolute(aniListID int, season int, episode float64) (float64, bool) {
+	for _, entry := range m.entries[aniListID] {
+		if entry.Season == season && episode >= 1 && episode < float64(entry.Episodes+1) {
+			return episode + float64(entry.AbsoluteStart-1), true
+		}
+	}
+	return 0, false
+}
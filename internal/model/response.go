@@ -36,6 +36,13 @@ type AnilistInfo struct {
 	Raw      any      `json:"-"` // To hold raw data if parsing fails
 }
 
+// FuzzyDate holds a possibly incomplete date as returned by AniList
+type FuzzyDate struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
 // Title holds the title information of the anime
 type Title struct {
 	Native  string `json:"native"`
@@ -43,6 +50,16 @@ type Title struct {
 	English string `json:"english"`
 }
 
+// Preferred returns the English title, falling back to the Romaji and Native title
+func (t Title) Preferred() string {
+	for _, title := range []string{t.English, t.Romaji, t.Native} {
+		if title != "" {
+			return title
+		}
+	}
+	return ""
+}
+
 // UnmarshalJSON custom unmarshal to handle unexpected formats for anilist info
 func (a *AnilistInfo) UnmarshalJSON(data []byte) error {
 	// Define a temporary struct with the expected structure
@@ -71,6 +88,11 @@ type EpisodeNumber struct {
 
 // UnmarshalJSON custom unmarshal to handle both string and float formats for episode numbers
 func (e *EpisodeNumber) UnmarshalJSON(data []byte) error {
+	// Movies and some specials have no episode number at all
+	if string(data) == "null" {
+		return nil
+	}
+
 	var num float64
 	// Try parsing as a float
 	if err := json.Unmarshal(data, &num); err == nil {
@@ -0,0 +1,42 @@
+// Package prompt asks the user questions on the standard input, shared by the renamer and the AniList ID detection.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stdin is shared by all prompts, so input piped ahead of a prompt is not lost between readers
+var stdin = bufio.NewReader(os.Stdin)
+
+// IsTerminal reports whether the standard input is an interactive terminal
+func IsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// YesNo asks a y/n question until it is answered. A closed input counts as no, so prompts never hang.
+func YesNo(question string) bool {
+	for {
+		fmt.Print(question)
+		input, err := stdin.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+
+		if input == "y" {
+			return true
+		} else if input == "n" {
+			return false
+		} else if err != nil {
+			fmt.Println()
+			fmt.Println("⚠️	No input available, answering no.")
+			return false
+		} else {
+			fmt.Println("❌	Invalid input. Please type 'y' for yes or 'n' for no.")
+		}
+	}
+}
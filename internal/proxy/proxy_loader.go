@@ -4,7 +4,6 @@ package proxy
 import (
 	"bufio"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,12 +11,15 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend" // Import the backend package for SearchBackend
 )
 
 // ProxyLoader handles loading and validating proxies from a file
 type ProxyLoader struct {
-	proxyList []ProxyDetails // List of validated working proxies
-	mu        sync.Mutex     // Mutex to safely update the proxy list
+	proxyList []ProxyDetails        // List of validated working proxies
+	mu        sync.Mutex            // Mutex to safely update the proxy list
+	backend   backend.SearchBackend // Search backend whose quota endpoint is used to check the proxies
 }
 
 // ProxyDetails holds information about a proxy, including its URL and quota status.
@@ -27,9 +29,9 @@ type ProxyDetails struct {
 	QuotaUsed int // QuotaUsed is the number of requests made using the proxy
 }
 
-// NewProxyLoader creates a new ProxyLoader
-func NewProxyLoader() *ProxyLoader {
-	return &ProxyLoader{}
+// NewProxyLoader creates a new ProxyLoader checking proxies against the given search backend
+func NewProxyLoader(searchBackend backend.SearchBackend) *ProxyLoader {
+	return &ProxyLoader{backend: searchBackend}
 }
 
 // LoadProxies loads proxies from a given file path concurrently, supporting authentication
@@ -109,7 +111,7 @@ func (pl *ProxyLoader) LoadProxies(filePath string) error {
 	return nil
 }
 
-// checkProxy tests the connectivity of a proxy URL and checks the quota status from the /me endpoint.
+// checkProxy tests the connectivity of a proxy URL and checks the quota status from the backend's quota endpoint.
 func (pl *ProxyLoader) checkProxy(proxyURL *url.URL) (bool, *ProxyDetails) {
 	transport := &http.Transport{
 		Proxy: http.ProxyURL(proxyURL),
@@ -130,47 +132,15 @@ func (pl *ProxyLoader) checkProxy(proxyURL *url.URL) (bool, *ProxyDetails) {
 		Timeout:   10 * time.Second, // Adjust the timeout if necessary
 	}
 
-	// Create a new request with headers
-	req, err := http.NewRequest("GET", "https://api.trace.moe/me", nil)
-	if err != nil {
-		fmt.Printf("Request creation error: %v\n", err)
-		return false, nil
-	}
-
-	// Set headers to mimic a browser request for better compatibility - not necessary for api.trace.moe but may be useful for other "APIs" that require it (soontm)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3")
-
-	// Send the request and check the response
-	resp, err := client.Do(req)
+	// Query the quota endpoint of the search backend through the proxy
+	result, err := pl.backend.Quota(client)
 	if err != nil {
 		fmt.Printf("Proxy error with %s: %v\n", proxyURL.String(), err)
 		return false, nil
 	}
-	defer resp.Body.Close()
-
-	// Check if the status code is OK
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Proxy responded with status code: %d for %s\n", resp.StatusCode, proxyURL.String())
-		return false, nil
-	}
-
-	// Parse the JSON response from /me endpoint
-	var result struct {
-		ID          string `json:"id"`
-		Priority    int    `json:"priority"`
-		Concurrency int    `json:"concurrency"`
-		Quota       int    `json:"quota"`
-		QuotaUsed   int    `json:"quotaUsed"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		fmt.Printf("Failed to parse /me endpoint response for %s: %v\n", proxyURL.String(), err)
-		return false, nil
-	}
 
 	// Calculate the remaining quota
-	remainingQuota := result.Quota - result.QuotaUsed
+	remainingQuota := result.Remaining()
 
 	// Create a ProxyDetails struct to store the proxy and its quota info
 	proxyDetails := &ProxyDetails{
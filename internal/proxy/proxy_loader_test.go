@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend"
+	"github.com/WhereIsF1/FumoFinder/internal/tracemoetest"
+)
+
+// writeProxyFile writes the proxy addresses to a proxy file, one per line
+func writeProxyFile(t *testing.T, proxies ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(proxies, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProxies(t *testing.T) {
+	tests := []struct {
+		name      string
+		quota     int // Searches allowed by the fake server
+		used      int // Searches already used
+		failing   bool
+		listed    int // Proxies returned by the loader
+		remaining int // Searches left reported for the listed proxy
+	}{
+		{"quota left", 1000, 10, false, 1, 990},
+		{"quota used up", 1000, 1000, false, 1, 0}, // Still listed, the identifier stops sending through it on the first 402
+		{"proxy failing", 1000, 10, true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tracemoetest.NewServer()
+			defer server.Close()
+			server.SetQuota(tt.quota, tt.used)
+
+			forwarder := tracemoetest.NewProxy()
+			defer forwarder.Close()
+			forwarder.SetFailing(tt.failing)
+
+			loader := NewProxyLoader(server.Backend(backend.TraceMoeConfig{}))
+			if err := loader.LoadProxies(writeProxyFile(t, forwarder.URL())); err != nil {
+				t.Fatal(err)
+			}
+
+			if proxies := loader.GetProxyList(); len(proxies) != tt.listed {
+				t.Errorf("got proxies %v, want %d", proxies, tt.listed)
+			}
+			for _, details := range loader.proxyList {
+				if remaining := details.Quota - details.QuotaUsed; remaining != tt.remaining {
+					t.Errorf("proxy %s has %d searches left, want %d", details.URL, remaining, tt.remaining)
+				}
+			}
+			if forwarder.Requests() == 0 {
+				t.Error("quota was not checked through the proxy")
+			}
+		})
+	}
+}
+
+func TestLoadProxiesSkipsFailingProxies(t *testing.T) {
+	server := tracemoetest.NewServer()
+	defer server.Close()
+
+	working := tracemoetest.NewProxy()
+	defer working.Close()
+	failing := tracemoetest.NewProxy()
+	defer failing.Close()
+	failing.SetFailing(true)
+
+	loader := NewProxyLoader(server.Backend(backend.TraceMoeConfig{}))
+	if err := loader.LoadProxies(writeProxyFile(t, working.URL(), failing.URL(), strings.TrimPrefix(working.URL(), "http://"))); err != nil {
+		t.Fatal(err)
+	}
+
+	proxies := loader.GetProxyList()
+	if len(proxies) != 2 {
+		t.Fatalf("got %d proxies, want the working proxy with and without scheme", len(proxies))
+	}
+	for _, proxyURL := range proxies {
+		if proxyURL.String() != working.URL() {
+			t.Errorf("unexpected proxy %s", proxyURL)
+		}
+	}
+}
+
+func TestLoadProxiesMissingFile(t *testing.T) {
+	loader := NewProxyLoader(backend.NewSelfHostedTraceMoe("http://127.0.0.1:1", backend.TraceMoeConfig{}))
+	if err := loader.LoadProxies(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected an error for a missing proxy file")
+	}
+}
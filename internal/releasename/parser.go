@@ -0,0 +1,104 @@
+// internal/releasename/parser.go
+package releasename
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Release holds the information parsed from a release file name
+type Release struct {
+	Group      string // Release group, e.g. "SubsPlease"
+	Title      string // Show title as written in the file name
+	Season     int    // Season number (0 if not present)
+	Episode    string // Episode number without leading zeros, e.g. "5" or "12.5" (empty if not present)
+	Resolution string // Resolution, e.g. "1080p"
+	CRC        string // CRC32 checksum, e.g. "ABCD1234"
+	Version    int    // Release version, e.g. 2 for "05v2" (0 if not present)
+}
+
+var (
+	groupPattern      = regexp.MustCompile(`^\[([^\]]+)\]`)
+	crcPattern        = regexp.MustCompile(`[\[(]([0-9A-Fa-f]{8})[\])]`)
+	resolutionPattern = regexp.MustCompile(`(?i)\b(\d{3,4}p|\d{3,4}x\d{3,4}|4k)\b`)
+	bracketPattern    = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+
+	// Episode patterns, tried in order from most to least specific
+	seasonEpisodePattern = regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,4}(?:\.\d)?)(?:v(\d))?\b`)
+	episodeWordPattern   = regexp.MustCompile(`(?i)\b(?:episode|ep|e)[ ._]?(\d{1,4}(?:\.\d)?)(?:v(\d))?\b`)
+	dashEpisodePattern   = regexp.MustCompile(`\s-\s(\d{1,4}(?:\.\d)?)(?:v(\d))?(?:\s|$)`)
+	seasonWordPattern    = regexp.MustCompile(`(?i)\b(?:season\s?|s)(\d{1,2})\b`)
+)
+
+// Parse extracts the group, title, season, episode, version, resolution and CRC from a release file name
+func Parse(fileName string) Release {
+	name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	var release Release
+
+	if match := groupPattern.FindStringSubmatch(name); match != nil {
+		release.Group = strings.TrimSpace(match[1])
+	}
+	if match := crcPattern.FindStringSubmatch(name); match != nil {
+		release.CRC = strings.ToUpper(match[1])
+	}
+	if match := resolutionPattern.FindStringSubmatch(name); match != nil {
+		release.Resolution = strings.ToLower(match[1])
+	}
+
+	// Strip bracketed tags so they are not mistaken for the episode or the title
+	stripped := strings.TrimSpace(bracketPattern.ReplaceAllString(name, " "))
+
+	// Dotted scene names use dots instead of spaces
+	if !strings.Contains(stripped, " ") {
+		stripped = strings.NewReplacer(".", " ", "_", " ").Replace(stripped)
+	}
+
+	titleEnd := len(stripped)
+	if match := seasonEpisodePattern.FindStringSubmatchIndex(stripped); match != nil {
+		release.Season, _ = strconv.Atoi(stripped[match[2]:match[3]])
+		release.Episode = normalizeEpisode(stripped[match[4]:match[5]])
+		release.Version = parseVersion(stripped, match[6:8])
+		titleEnd = match[0]
+	} else if match := dashEpisodePattern.FindStringSubmatchIndex(stripped + " "); match != nil {
+		release.Episode = normalizeEpisode(stripped[match[2]:match[3]])
+		release.Version = parseVersion(stripped, match[4:6])
+		titleEnd = match[0]
+	} else if match := episodeWordPattern.FindStringSubmatchIndex(stripped); match != nil {
+		release.Episode = normalizeEpisode(stripped[match[2]:match[3]])
+		release.Version = parseVersion(stripped, match[4:6])
+		titleEnd = match[0]
+	}
+
+	title := stripped[:min(titleEnd, len(stripped))]
+
+	// A season written next to the title, e.g. "Show S2 - 05" or "Show Season 2 - 05"
+	if release.Season == 0 {
+		if match := seasonWordPattern.FindStringSubmatchIndex(title); match != nil {
+			release.Season, _ = strconv.Atoi(title[match[2]:match[3]])
+			title = title[:match[0]]
+		}
+	}
+
+	release.Title = strings.Trim(strings.TrimSpace(title), "-_. ")
+	return release
+}
+
+// parseVersion returns the release version captured at the submatch bounds, or 0 if the version group did not match
+func parseVersion(name string, bounds []int) int {
+	if bounds[0] < 0 {
+		return 0
+	}
+	version, _ := strconv.Atoi(name[bounds[0]:bounds[1]])
+	return version
+}
+
+// normalizeEpisode strips leading zeros from an episode number, e.g. "05" becomes "5"
+func normalizeEpisode(episode string) string {
+	number, err := strconv.ParseFloat(episode, 64)
+	if err != nil {
+		return episode
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
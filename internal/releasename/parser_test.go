@@ -0,0 +1,28 @@
+package releasename
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Release
+	}{
+		{"[SubsPlease] Show - 05 (1080p) [ABCD1234].mkv", Release{Group: "SubsPlease", Title: "Show", Episode: "5", Resolution: "1080p", CRC: "ABCD1234"}},
+		{"[Group] Show Title - 12.5 [720p].mkv", Release{Group: "Group", Title: "Show Title", Episode: "12.5", Resolution: "720p"}},
+		{"[Group] Show - 05v2 [1080p].mkv", Release{Group: "Group", Title: "Show", Episode: "5", Resolution: "1080p", Version: 2}},
+		{"[Group] Show S2 - 03 [1080p].mkv", Release{Group: "Group", Title: "Show", Season: 2, Episode: "3", Resolution: "1080p"}},
+		{"[Group] Show Season 3 - 01.mkv", Release{Group: "Group", Title: "Show", Season: 3, Episode: "1"}},
+		{"Show.Title.S02E07.1080p.WEB.mkv", Release{Title: "Show Title", Season: 2, Episode: "7", Resolution: "1080p"}},
+		{"Show.S01E03v3.720p.mkv", Release{Title: "Show", Season: 1, Episode: "3", Resolution: "720p", Version: 3}},
+		{"Show Episode 08.mkv", Release{Title: "Show", Episode: "8"}},
+		{"Show_-_Ep10_[BD 1920x1080].mkv", Release{Title: "Show", Episode: "10", Resolution: "1920x1080"}},
+		{"[Group] Movie Title (2019) [BD 4K].mkv", Release{Group: "Group", Title: "Movie Title", Resolution: "4k"}},
+		{"/videos/Season 1/[Group] Show - 100 [1080p].mkv", Release{Group: "Group", Title: "Show", Episode: "100", Resolution: "1080p"}},
+	}
+
+	for _, tt := range tests {
+		if got := Parse(tt.name); got != tt.want {
+			t.Errorf("Parse(%q)\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
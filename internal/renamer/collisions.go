@@ -0,0 +1,228 @@
+package renamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/releasename" // Import the releasename package for the resolution of duplicates
+)
+
+// CollisionPolicy decides what happens when several files would get the same name, or the name is already taken
+type CollisionPolicy string
+
+const (
+	CollisionSkip       CollisionPolicy = "skip"       // Skip every file involved in the collision
+	CollisionSuffix     CollisionPolicy = "suffix"     // Mark the duplicates with their release version, e.g. Title.E05.v2.mkv, or number them, e.g. Title.E05 (1).mkv
+	CollisionKeepBest   CollisionPolicy = "keep-best"  // Rename the file with the highest resolution, or the largest one, and leave the others alone
+	CollisionQuarantine CollisionPolicy = "quarantine" // Rename the best file and move the duplicates into the quarantine folder
+)
+
+// DefaultQuarantineFolder is the folder next to the original file receiving duplicates if no quarantine folder is set
+const DefaultQuarantineFolder = "duplicates"
+
+// ParseCollisionPolicy parses a collision policy name
+func ParseCollisionPolicy(name string) (CollisionPolicy, error) {
+	switch policy := CollisionPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case CollisionSkip, CollisionSuffix, CollisionKeepBest, CollisionQuarantine:
+		return policy, nil
+	case "":
+		return CollisionSkip, nil
+	default:
+		return "", fmt.Errorf("unknown collision policy: %s (expected skip, suffix, keep-best or quarantine)", name)
+	}
+}
+
+// SetCollisionPolicy sets how name collisions are resolved and the folder receiving quarantined duplicates
+// (empty for a duplicates folder next to each file).
+func (fr *FileRenamer) SetCollisionPolicy(policy CollisionPolicy, quarantineDir string) {
+	fr.collisions = policy
+	fr.quarantineDir = strings.TrimSpace(quarantineDir)
+}
+
+// resolveCollisions detects files sharing a target with each other or with an existing file and applies the collision policy.
+// Files that already carry their new name are dropped from the plan; names differing only in case count as collisions,
+// but a file may still change the case of its own name.
+func (fr *FileRenamer) resolveCollisions(plan []plannedRename, report *renameReport) []plannedRename {
+	var keys []string
+	groups := make(map[string][]plannedRename)
+	for _, rename := range plan {
+		if filepath.Clean(rename.Source) == filepath.Clean(rename.Target) {
+			fr.skip(report, rename.Source, "already named correctly")
+			continue
+		}
+		key := collisionKey(rename.Target)
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rename)
+	}
+
+	// Targets claimed by the plan, so numbered and quarantined names never collide again
+	taken := make(map[string]bool)
+	for _, key := range keys {
+		taken[key] = true
+	}
+
+	var resolved []plannedRename
+	for _, key := range keys {
+		group := groups[key]
+		target := group[0].Target
+		existing := existsApartFrom(target, group)
+		if len(group) == 1 && !existing {
+			resolved = append(resolved, group[0])
+			continue
+		}
+
+		// Order the candidates from the best to the worst file
+		sort.SliceStable(group, func(i, j int) bool {
+			return betterFile(group[i].Source, group[j].Source)
+		})
+		if existing {
+			fmt.Printf("⚠️	The new name %s is already taken by an existing file.\n", fr.displayPath(group[0].Source, target))
+		} else {
+			fmt.Printf("⚠️	%d files would be renamed to %s.\n", len(group), fr.displayPath(group[0].Source, target))
+		}
+
+		switch fr.collisions {
+		case CollisionSuffix:
+			for i, rename := range group {
+				if i > 0 || existing {
+					rename.Target = suffixPath(rename, taken)
+				}
+				resolved = append(resolved, rename)
+			}
+		case CollisionKeepBest:
+			best := group[0]
+			if existing && !betterFile(best.Source, target) {
+				for _, rename := range group {
+					fr.skip(report, rename.Source, "the existing "+filepath.Base(target)+" is at least as good")
+				}
+				continue
+			}
+			if existing {
+				// The worse existing file makes room for the best one
+				resolved = append(resolved, plannedRename{Source: target, Target: fr.quarantinePath(target, taken), Name: resolvedName{Title: "duplicate", Confidence: best.Name.Confidence}, Displaced: true})
+			}
+			resolved = append(resolved, best)
+			for _, rename := range group[1:] {
+				fr.skip(report, rename.Source, "kept the better "+filepath.Base(best.Source))
+			}
+		case CollisionQuarantine:
+			for i, rename := range group {
+				if i > 0 || existing {
+					rename.Target = fr.quarantinePath(rename.Source, taken)
+				}
+				resolved = append(resolved, rename)
+			}
+		default:
+			for _, rename := range group {
+				if existing {
+					fr.skip(report, rename.Source, "target "+filepath.Base(target)+" already exists")
+				} else {
+					fr.skip(report, rename.Source, fmt.Sprintf("%d files would be renamed to %s", len(group), filepath.Base(target)))
+				}
+			}
+		}
+	}
+	return resolved
+}
+
+// existsApartFrom reports whether a file other than the sources of the group occupies the target,
+// so a case-only rename on a case-insensitive file system is not mistaken for a collision with itself.
+func existsApartFrom(target string, group []plannedRename) bool {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+	for _, rename := range group {
+		if source, err := os.Lstat(rename.Source); err == nil && os.SameFile(info, source) {
+			return false
+		}
+	}
+	return true
+}
+
+// suffixPath returns a free path for a duplicate: marked with the release version of the original file name
+// like "name.v2.ext" if it has one, numbered like "name (1).ext" otherwise.
+func suffixPath(rename plannedRename, taken map[string]bool) string {
+	if version := releasename.Parse(filepath.Base(rename.Source)).Version; version > 1 {
+		ext := filepath.Ext(rename.Target)
+		candidate := fmt.Sprintf("%s.v%d%s", strings.TrimSuffix(rename.Target, ext), version, ext)
+		if _, err := os.Lstat(candidate); err != nil && !taken[collisionKey(candidate)] {
+			taken[collisionKey(candidate)] = true
+			return candidate
+		}
+	}
+	return freePath(rename.Target, taken)
+}
+
+// quarantinePath returns a free path for a duplicate in the quarantine folder, keeping its original name.
+func (fr *FileRenamer) quarantinePath(source string, taken map[string]bool) string {
+	folder := fr.quarantineDir
+	if folder == "" {
+		folder = filepath.Join(filepath.Dir(source), DefaultQuarantineFolder)
+	}
+	return freePath(filepath.Join(folder, filepath.Base(source)), taken)
+}
+
+// freePath returns the path itself or numbers it as "name (1).ext", "name (2).ext", ... until it is neither taken by the plan
+// nor on disk, and claims it.
+func freePath(path string, taken map[string]bool) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 0; ; i++ {
+		candidate := path
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		if taken[collisionKey(candidate)] {
+			continue
+		}
+		if _, err := os.Lstat(candidate); err == nil {
+			continue
+		}
+		taken[collisionKey(candidate)] = true
+		return candidate
+	}
+}
+
+// collisionKey normalises a path for comparison, treating names differing only in case as the same file
+// like the case-insensitive file systems of Windows and macOS do.
+func collisionKey(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// betterFile reports whether file a is preferred over file b: higher resolution first, then larger size.
+func betterFile(a, b string) bool {
+	resolutionA, resolutionB := resolutionHeight(a), resolutionHeight(b)
+	if resolutionA != resolutionB {
+		return resolutionA > resolutionB
+	}
+	return fileSize(a) > fileSize(b)
+}
+
+// resolutionHeight returns the vertical resolution from a file name, e.g. 1080 for 1080p or 1920x1080 (0 if unknown).
+func resolutionHeight(path string) int {
+	resolution := releasename.Parse(filepath.Base(path)).Resolution
+	if resolution == "4k" {
+		return 2160
+	}
+	if _, height, ok := strings.Cut(resolution, "x"); ok {
+		resolution = height
+	}
+	height, _ := strconv.Atoi(strings.TrimSuffix(resolution, "p"))
+	return height
+}
+
+// fileSize returns the size of a file, or 0 if it cannot be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
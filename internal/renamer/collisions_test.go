@@ -0,0 +1,133 @@
+package renamer
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResolveCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CollisionPolicy
+		files   map[string]int // Files on disk and their sizes
+		renames [][2]string    // Planned renames from source to target name
+		want    []string       // Resolved renames as "source -> target"
+		skipped []string       // Skipped sources
+	}{
+		{
+			name:    "distinct targets",
+			policy:  CollisionSkip,
+			files:   map[string]int{"a - 01.mkv": 1, "a - 02.mkv": 1},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}, {"a - 02.mkv", "Show.E02.mkv"}},
+			want:    []string{"a - 01.mkv -> Show.E01.mkv", "a - 02.mkv -> Show.E02.mkv"},
+		},
+		{
+			name:    "already named",
+			policy:  CollisionSkip,
+			files:   map[string]int{"Show.E01.mkv": 1},
+			renames: [][2]string{{"Show.E01.mkv", "Show.E01.mkv"}},
+			skipped: []string{"Show.E01.mkv"},
+		},
+		{
+			name:    "case-only rename",
+			policy:  CollisionSkip,
+			files:   map[string]int{"show.e01.mkv": 1},
+			renames: [][2]string{{"show.e01.mkv", "Show.E01.mkv"}},
+			want:    []string{"show.e01.mkv -> Show.E01.mkv"},
+		},
+		{
+			name:    "skip duplicates",
+			policy:  CollisionSkip,
+			files:   map[string]int{"a - 01.mkv": 1, "b - 01.mkv": 1},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}, {"b - 01.mkv", "Show.E01.mkv"}},
+			skipped: []string{"a - 01.mkv", "b - 01.mkv"},
+		},
+		{
+			name:    "skip existing target",
+			policy:  CollisionSkip,
+			files:   map[string]int{"a - 01.mkv": 1, "Show.E01.mkv": 1},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}},
+			skipped: []string{"a - 01.mkv"},
+		},
+		{
+			name:    "suffix with release version",
+			policy:  CollisionSuffix,
+			files:   map[string]int{"[G] Show - 01 [1080p].mkv": 2, "[G] Show - 01v2 [1080p].mkv": 1},
+			renames: [][2]string{{"[G] Show - 01v2 [1080p].mkv", "Show.E01.mkv"}, {"[G] Show - 01 [1080p].mkv", "Show.E01.mkv"}},
+			want:    []string{"[G] Show - 01 [1080p].mkv -> Show.E01.mkv", "[G] Show - 01v2 [1080p].mkv -> Show.E01.v2.mkv"},
+		},
+		{
+			name:    "suffix numbered",
+			policy:  CollisionSuffix,
+			files:   map[string]int{"a - 01.mkv": 2, "b - 01.mkv": 1, "Show.E01.mkv": 1},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}, {"b - 01.mkv", "Show.E01.mkv"}},
+			want:    []string{"a - 01.mkv -> Show.E01 (1).mkv", "b - 01.mkv -> Show.E01 (2).mkv"},
+		},
+		{
+			name:    "keep best resolution",
+			policy:  CollisionKeepBest,
+			files:   map[string]int{"[G] Show - 01 [720p].mkv": 5, "[G] Show - 01 [1080p].mkv": 1},
+			renames: [][2]string{{"[G] Show - 01 [720p].mkv", "Show.E01.mkv"}, {"[G] Show - 01 [1080p].mkv", "Show.E01.mkv"}},
+			want:    []string{"[G] Show - 01 [1080p].mkv -> Show.E01.mkv"},
+			skipped: []string{"[G] Show - 01 [720p].mkv"},
+		},
+		{
+			name:    "keep best displaces worse existing file",
+			policy:  CollisionKeepBest,
+			files:   map[string]int{"a - 01.mkv": 5, "Show.E01.mkv": 1},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}},
+			want:    []string{"Show.E01.mkv -> duplicates/Show.E01.mkv", "a - 01.mkv -> Show.E01.mkv"},
+		},
+		{
+			name:    "keep best leaves better existing file",
+			policy:  CollisionKeepBest,
+			files:   map[string]int{"a - 01.mkv": 1, "Show.E01.mkv": 5},
+			renames: [][2]string{{"a - 01.mkv", "Show.E01.mkv"}},
+			skipped: []string{"a - 01.mkv"},
+		},
+		{
+			name:    "quarantine duplicates",
+			policy:  CollisionQuarantine,
+			files:   map[string]int{"a - 01.mkv": 5, "b - 01.mkv": 1},
+			renames: [][2]string{{"b - 01.mkv", "Show.E01.mkv"}, {"a - 01.mkv", "Show.E01.mkv"}},
+			want:    []string{"a - 01.mkv -> Show.E01.mkv", "b - 01.mkv -> duplicates/b - 01.mkv"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			fr := NewFileRenamer(dir)
+			fr.SetCollisionPolicy(tt.policy, "")
+			var plan []plannedRename
+			for _, rename := range tt.renames {
+				plan = append(plan, plannedRename{Source: filepath.Join(dir, rename[0]), Target: filepath.Join(dir, rename[1])})
+			}
+
+			report := &renameReport{}
+			var got []string
+			for _, rename := range fr.resolveCollisions(plan, report) {
+				source, _ := filepath.Rel(dir, rename.Source)
+				target, _ := filepath.Rel(dir, rename.Target)
+				got = append(got, source+" -> "+filepath.ToSlash(target))
+			}
+			var skipped []string
+			for _, entry := range report.skipped {
+				skipped = append(skipped, entry.File)
+			}
+			sort.Strings(got)
+			sort.Strings(skipped)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolved = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package renamer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/WhereIsF1/FumoFinder/internal/identifier" // Import the identifier package for media kinds
+	"github.com/WhereIsF1/FumoFinder/internal/tagging"    // Import the tagging package for container metadata
+)
+
+// SetTagger enables writing the title, episode, season and AniList ID into the renamed videos.
+func (fr *FileRenamer) SetTagger(tagger *tagging.Tagger) {
+	fr.tagger = tagger
+}
+
+// writeTags writes the identification of a renamed video into its container. Hardlinks and symlinks are left alone,
+// as rewriting them would change or detach the original, e.g. a seeding torrent.
+func (fr *FileRenamer) writeTags(mode OutputMode, rename plannedRename) {
+	if mode == ModeHardlink || mode == ModeSymlink {
+		fmt.Printf("ℹ️	Not writing tags into %s, it is %s to the original.\n", filepath.Base(rename.Target), mode.verb())
+		return
+	}
+
+	if err := fr.tagger.Tag(rename.Target, containerTags(rename.Name)); err != nil {
+		fmt.Printf("⚠️	Failed to write tags into %s: %v\n", filepath.Base(rename.Target), err)
+		return
+	}
+	fmt.Printf("🏷️	Wrote tags into %s\n", filepath.Base(rename.Target))
+}
+
+// containerTags builds the container metadata of a resolved name
+func containerTags(name resolvedName) tagging.Tags {
+	tags := tagging.Tags{
+		Title:     name.Match.EpisodeTitle,
+		Show:      name.Title,
+		AniListID: name.Match.AnilistID,
+	}
+	if name.Kind == identifier.Movie {
+		if tags.Title == "" {
+			tags.Title = name.Title
+		}
+		return tags
+	}
+
+	tags.EpisodeID = name.Episode
+	if tags.Title == "" {
+		tags.Title = name.Title + " - " + padNumber(2, name.Episode)
+	}
+	switch {
+	case name.Kind == identifier.Special:
+		tags.Season, tags.HasSeason = 0, true
+	case name.Season > 0:
+		tags.Season, tags.HasSeason = name.Season, true
+	}
+	return tags
+}
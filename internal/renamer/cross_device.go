@@ -0,0 +1,13 @@
+//go:build !windows
+
+package renamer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice reports whether a rename or link failed because source and target are on different file systems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
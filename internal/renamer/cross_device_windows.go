@@ -0,0 +1,14 @@
+package renamer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when a file is moved or linked to another drive
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice reports whether a rename or link failed because source and target are on different drives.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
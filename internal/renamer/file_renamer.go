@@ -1,31 +1,121 @@
 package renamer
 
 import (
-	"bufio"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/WhereIsF1/FumoFinder/internal/identifier" // Import the identifier package for MatchInfo
+	"github.com/WhereIsF1/FumoFinder/internal/anilist"     // Import the anilist package for season detection
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"  // Import the identifier package for MatchInfo
+	"github.com/WhereIsF1/FumoFinder/internal/mapping"     // Import the mapping package for episode numbering schemes
+	"github.com/WhereIsF1/FumoFinder/internal/model"       // Import the model package for EpisodeNumber
+	"github.com/WhereIsF1/FumoFinder/internal/prompt"      // Import the prompt package for confirmations
+	"github.com/WhereIsF1/FumoFinder/internal/releasename" // Import the releasename package for file name hints
+	"github.com/WhereIsF1/FumoFinder/internal/tagging"     // Import the tagging package for container metadata
 )
 
 // FileRenamer handles renaming MKV files based on the majority episode result.
 type FileRenamer struct {
 	results     map[string][]identifier.MatchInfo // Map of MKV file name to a list of MatchInfo structs
 	inputFolder string                            // Path to the folder where the MKV files are located
+	specials    SpecialsTable                     // Table mapping specials to S00 episode numbers
+	seasons     *anilist.SeasonResolver           // Resolver for season numbers, nil if seasons are not detected
+	episodeMap  *mapping.Mapper                   // Mapper between absolute and seasonal numbering, nil if not loaded
+	scheme      mapping.Scheme                    // Episode numbering scheme used for the new file names
+	template    *NameTemplate                     // Template rendering the new file names
+	idTagForm   string                            // Format of the AniList ID tag available to templates, empty to disable
+	outputDir   string                            // Folder the rendered paths are relative to, empty for the folder of the original file
+
+	policy        RenamePolicy // Decides which planned renames are carried out
+	minConfidence float64      // Confidence required by RenameAutoIfConfident
+	journal       *Journal     // Journal recording every rename, nil to disable
+
+	mode          OutputMode      // Decides how files get their new names
+	tagger        *tagging.Tagger // Writes the identification into the renamed videos, nil to disable
+	nfo           bool            // Generates Kodi/Jellyfin NFO files next to the renamed videos
+	nfoShows      map[string]bool // tvshow.nfo files already written by this run
+	collisions    CollisionPolicy // Decides what happens when targets collide
+	quarantineDir string          // Folder receiving quarantined duplicates, empty for a folder next to each file
+}
+
+// resolvedName holds everything needed to construct the new name of a file.
+type resolvedName struct {
+	Title   string               // Series title
+	Episode string               // Episode label, "first-last" for multi-episode files
+	Kind    identifier.MediaKind // Kind of media, decides the naming scheme
+	Year    int                  // Year the media started airing (0 if unknown)
+	Season  int                  // Season number within the franchise (0 if not detected)
+
+	Match              identifier.MatchInfo // Match of the majority title, source of the template fields
+	Similarity         float64              // Average similarity of the frames matching the episode in percent
+	Confidence         float64              // Share of frames agreeing on the episode
+	RunnerUp           string               // Second most voted episode (empty if all frames agree)
+	RunnerUpConfidence float64              // Share of frames voting for the runner-up episode
+	SeriesID           int                  // AniList ID of the first season of the franchise (0 if seasons are not detected)
 }
 
 // NewFileRenamer creates a new FileRenamer with the given input folder.
 func NewFileRenamer(inputFolder string) *FileRenamer {
+	defaultTemplate, _ := ParseNameTemplate(DefaultNameTemplate, sanitizers["strict"])
 	return &FileRenamer{
 		results:     make(map[string][]identifier.MatchInfo),
 		inputFolder: strings.TrimSpace(inputFolder), // Trim spaces from the folder path
+		scheme:      mapping.SchemeSource,
+		template:    defaultTemplate,
+
+		policy:        RenameAsk,
+		minConfidence: identifier.ConfidenceWarningLevel,
+		mode:          ModeRename,
+		collisions:    CollisionSkip,
 	}
 }
 
+// SetNameTemplate sets the template rendering the new file names.
+func (fr *FileRenamer) SetNameTemplate(template *NameTemplate) {
+	fr.template = template
+}
+
+// SetRenamePolicy sets which planned renames are carried out and the confidence required by RenameAutoIfConfident.
+func (fr *FileRenamer) SetRenamePolicy(policy RenamePolicy, minConfidence float64) {
+	fr.policy = policy
+	fr.minConfidence = minConfidence
+}
+
+// SetJournal records every rename in the journal, so the run can be undone.
+func (fr *FileRenamer) SetJournal(journal *Journal) {
+	fr.journal = journal
+}
+
+// SetSpecialsTable sets the table used to map specials to S00 episode numbers.
+func (fr *FileRenamer) SetSpecialsTable(specials SpecialsTable) {
+	fr.specials = specials
+}
+
+// SetSeasonResolver enables season detection, naming series as Series.SxxEyy.
+func (fr *FileRenamer) SetSeasonResolver(seasons *anilist.SeasonResolver) {
+	fr.seasons = seasons
+}
+
+// SetEpisodeMapping sets the mapper and the numbering scheme used for the new file names.
+func (fr *FileRenamer) SetEpisodeMapping(episodeMap *mapping.Mapper, scheme mapping.Scheme) {
+	fr.episodeMap = episodeMap
+	fr.scheme = scheme
+}
+
+// SetIDTagFormat enables AniList ID tags like {anilist-12345} in the names, available to templates as .IDTag.
+func (fr *FileRenamer) SetIDTagFormat(format string) {
+	fr.idTagForm = format
+}
+
+// SetOutputFolder sets the folder the rendered names are relative to, e.g. the root of a media library.
+func (fr *FileRenamer) SetOutputFolder(outputDir string) {
+	fr.outputDir = strings.TrimSpace(outputDir)
+}
+
 // AddResult adds an identification result for an MKV file using MatchInfo.
 func (fr *FileRenamer) AddResult(match identifier.MatchInfo) {
 	// Add the MatchInfo to the list associated with the MKV file name
@@ -33,169 +123,376 @@ func (fr *FileRenamer) AddResult(match identifier.MatchInfo) {
 }
 
 // RenameFiles renames the MKV files based on the majority episode number and title.
+// The renames are planned first and then carried out according to the rename policy, followed by a report.
 func (fr *FileRenamer) RenameFiles() {
-	fmt.Println()
-	fmt.Println("📝	Ready to rename files based on identified episodes.")
-	fmt.Println("⚠️	Confirm renaming each file or choose to skip.")
-	fmt.Println()
-
-	// Ask if the user wants to use bulk mode
-	if ConfirmBulkRename() {
-		// Bulk renaming mode
-		bulkPreview := make(map[string]string) // Store old and new file names
+	plan, report := fr.planRenames()
 
+	// Prompts would block unattended runs, so asking falls back to not renaming without a terminal
+	policy := fr.policy
+	if policy == RenameAsk && !prompt.IsTerminal() {
 		fmt.Println()
+		fmt.Println("⚠️	Standard input is not a terminal. Skipping renaming, use --rename auto or auto-if-confident for unattended runs.")
+		policy = RenameNever
+	}
 
-		// Generate preview of all renames
-		for mkvFile, matches := range fr.results {
-			if len(matches) == 0 {
-				fmt.Printf("❌	No episode results found for file: %s\n", mkvFile)
+	switch policy {
+	case RenameNever:
+		fr.displayPlan(plan)
+		for _, rename := range plan {
+			fr.skip(report, rename.Source, "renaming disabled (--rename never)")
+		}
+	case RenameAuto:
+		fr.executeRenames(plan, report)
+	case RenameAutoIfConfident:
+		var confident []plannedRename
+		for _, rename := range plan {
+			if rename.Name.Confidence < fr.minConfidence {
+				fr.skip(report, rename.Source, fmt.Sprintf("confidence %.0f%% is below %.0f%%", rename.Name.Confidence*100, fr.minConfidence*100))
 				continue
 			}
+			confident = append(confident, rename)
+		}
+		fr.executeRenames(confident, report)
+	default:
+		fr.askRenames(plan, report)
+	}
 
-			majorityTitle, majorityEpisode, confidence := findMajorityTitleAndEpisode(matches)
-			if majorityEpisode == "" || majorityTitle == "" {
-				fmt.Printf("❌	Failed to determine majority episode or title for file: %s\n", mkvFile)
-				continue
-			}
+	report.display()
+	if fr.journal != nil && len(report.renamed) > 0 {
+		fmt.Printf("↩️	Renames recorded as run %s, revert them with FumoFinder undo --run %s\n", fr.journal.RunID(), fr.journal.RunID())
+	}
+}
 
-			if confidence < 0.90 {
-				fmt.Printf("⚠️	The confidence level for episode %s is only %.0f%%. Results may not be reliable.\n", majorityEpisode, confidence*100)
-			}
+// planRenames resolves the new name of every identified file without touching the disk.
+// Files that cannot be named are added to the report as skipped.
+func (fr *FileRenamer) planRenames() ([]plannedRename, *renameReport) {
+	report := &renameReport{}
+
+	var mkvFiles []string
+	for mkvFile := range fr.results {
+		mkvFiles = append(mkvFiles, mkvFile)
+	}
+	sort.Strings(mkvFiles)
 
-			fullPath := filepath.Join(fr.inputFolder, strings.TrimSpace(mkvFile))
-			newFileName := constructNewFileName(fullPath, majorityTitle, majorityEpisode)
+	var plan []plannedRename
+	for _, mkvFile := range mkvFiles {
+		fullPath := filepath.Join(fr.inputFolder, strings.TrimSpace(mkvFile))
 
-			bulkPreview[fullPath] = newFileName
+		name, err := fr.resolveEpisode(mkvFile, fr.results[mkvFile])
+		if err != nil {
+			fmt.Printf("❌	%v\n", err)
+			fr.skip(report, fullPath, err.Error())
+			continue
 		}
 
-		// Show the user the old and new names for confirmation
-		fmt.Println()
-		fmt.Println("📋	Bulk Rename Preview:")
-		fmt.Println()
-		for oldName, newName := range bulkPreview {
-			fmt.Printf("➡️	Original: %s\n", filepath.Base(oldName))
-			fmt.Printf("➡️	New Name: %s\n\n", filepath.Base(newName))
+		// Check if the file exists before renaming
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			log.Printf("❌	File does not exist: %s\n", fullPath)
+			fr.skip(report, fullPath, "file does not exist")
+			continue
 		}
 
-		// Ask for confirmation to proceed with the bulk rename
-		fmt.Printf("↪️	Do you want to rename all files (y to confirm, n to cancel and go back to individual renaming)? ")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
+		newFileName, err := fr.newFilePath(fullPath, name)
+		if err != nil {
+			fmt.Printf("❌	%v\n", err)
+			fr.skip(report, fullPath, err.Error())
+			continue
+		}
+
+		plan = append(plan, plannedRename{Source: fullPath, Target: newFileName, Name: name})
+	}
+	return fr.attachSidecars(fr.resolveCollisions(plan, report), report), report
+}
 
-		if input == "y" {
-			// Proceed with bulk renaming
+// askRenames asks the user to confirm the planned renames, either all at once or file by file.
+func (fr *FileRenamer) askRenames(plan []plannedRename, report *renameReport) {
+	fmt.Println()
+	fmt.Println("📝	Ready to rename files based on identified episodes.")
+	fmt.Println("⚠️	Confirm renaming each file or choose to skip.")
+	fmt.Println()
+
+	// Ask if the user wants to use bulk mode
+	if ConfirmBulkRename() {
+		fr.displayPlan(plan)
+
+		// Ask for confirmation to proceed with the bulk rename
+		if prompt.YesNo("↪️	Do you want to rename all files (y to confirm, n to cancel and go back to individual renaming)? ") {
 			fmt.Println()
-			for oldName, newName := range bulkPreview {
-				if err := os.Rename(oldName, newName); err != nil {
-					fmt.Printf("❌	Failed to rename file %s: %v\n", oldName, err)
-				} else {
-					fmt.Printf("✅	Successfully renamed file to: %s\n", filepath.Base(newName))
-				}
-			}
+			fr.executeRenames(plan, report)
 			return // Exit after bulk renaming
+		}
+		fmt.Println("⏭️	Bulk renaming canceled. Proceeding with individual renaming.")
+	}
+
+	// Confirm each file individually
+	fmt.Println()
+	for _, rename := range plan {
+		fmt.Println()
+		fmt.Printf("📍	Renaming File:\n")
+		fmt.Printf("➡️	Original:  %s\n", filepath.Base(rename.Source))
+		fmt.Printf("➡️	New Name:  %s\n", fr.displayPath(rename.Source, rename.Target))
+		fmt.Printf("➡️	Confidence: %.0f%%\n", rename.Name.Confidence*100)
+		fmt.Printf("➡️	Mode:      %s\n", fr.mode)
+		fr.displaySidecars(rename)
+
+		if confirmRename() {
+			fmt.Println()
+			fr.executeRenames([]plannedRename{rename}, report)
 		} else {
-			fmt.Println("⏭️	Bulk renaming canceled. Proceeding with individual renaming.")
+			fmt.Println()
+			fmt.Printf("⏭️	Skipped renaming for file: %s\n", filepath.Base(rename.Source))
+			fr.skip(report, rename.Source, "declined by user")
 		}
+		fmt.Println()
 	}
+}
 
-	// Call individual renaming for each file
+// displayPlan shows the old and new names of all planned renames.
+func (fr *FileRenamer) displayPlan(plan []plannedRename) {
+	fmt.Println()
+	fmt.Printf("📋	Bulk Rename Preview (mode: %s):\n", fr.mode)
 	fmt.Println()
+	for _, rename := range plan {
+		fmt.Printf("➡️	Original: %s\n", filepath.Base(rename.Source))
+		fmt.Printf("➡️	New Name: %s (confidence %.0f%%)\n", fr.displayPath(rename.Source, rename.Target), rename.Name.Confidence*100)
+		fr.displaySidecars(rename)
+		fmt.Println()
+	}
+}
 
-	for mkvFile, matches := range fr.results {
-		fr.renameSingleFile(mkvFile, matches)
+// displaySidecars lists the new names of the sidecars moving along with a video.
+func (fr *FileRenamer) displaySidecars(rename plannedRename) {
+	for _, sidecar := range rename.Sidecars {
+		fmt.Printf("	 + %s -> %s\n", filepath.Base(sidecar.Source), fr.displayPath(rename.Source, sidecar.Target))
 	}
 }
 
-// renameSingleFile handles the renaming of individual files based on the most common title and episode.
-func (fr *FileRenamer) renameSingleFile(mkvFile string, matches []identifier.MatchInfo) {
-	if len(matches) == 0 {
-		fmt.Printf("❌	No episode results found for file: %s\n", mkvFile)
-		return
+// executeRenames carries out the given renames and records their outcome in the report.
+func (fr *FileRenamer) executeRenames(plan []plannedRename, report *renameReport) {
+	for _, rename := range plan {
+		// Existing files making room for a better one are always moved
+		mode := fr.mode
+		if rename.Displaced {
+			mode = ModeRename
+		}
+
+		used, err := transfer(mode, rename.Source, rename.Target)
+		if err != nil {
+			fmt.Printf("❌	Failed to rename file %s: %v\n", rename.Source, err)
+			report.failed = append(report.failed, reportEntry{File: fr.relativeSource(rename.Source), Reason: err.Error()})
+			continue
+		}
+		if used != mode {
+			fmt.Printf("ℹ️	%s is on another file system, %s it instead.\n", filepath.Base(rename.Source), used.verb())
+		}
+
+		// Tags are written before the journal entry, so undo accepts the tagged file
+		if fr.tagger != nil && !rename.Displaced {
+			fr.writeTags(used, rename)
+		}
+		fr.record(rename.Source, rename.Target, used)
+		fmt.Printf("✅	Successfully %s file to: %s\n", used.verb(), fr.displayPath(rename.Source, rename.Target))
+		report.renamed = append(report.renamed, reportEntry{File: fr.relativeSource(rename.Source), Target: fr.displayPath(rename.Source, rename.Target)})
+
+		// Sidecars follow their video, a failed sidecar does not undo the video
+		for _, sidecar := range rename.Sidecars {
+			if _, err := fr.move(mode, sidecar.Source, sidecar.Target); err != nil {
+				fmt.Printf("❌	Failed to rename sidecar %s: %v\n", sidecar.Source, err)
+				report.failed = append(report.failed, reportEntry{File: fr.relativeSource(sidecar.Source), Reason: err.Error()})
+				continue
+			}
+			report.renamed = append(report.renamed, reportEntry{File: fr.relativeSource(sidecar.Source), Target: fr.displayPath(rename.Source, sidecar.Target)})
+		}
+
+		// NFO files are written after the sidecars, so an existing NFO sidecar is kept rather than blocked
+		if fr.nfo && !rename.Displaced {
+			fr.writeNFOFiles(rename)
+		}
 	}
+}
 
-	// Determine the most common title and episode number
-	majorityTitle, majorityEpisode, confidence := findMajorityTitleAndEpisode(matches)
-	if majorityEpisode == "" || majorityTitle == "" {
-		fmt.Printf("❌	Failed to determine majority episode or title for file: %s\n", mkvFile)
+// move gives a single file or folder its new name using the output mode and records it in the journal.
+// It returns the mode actually used, see transfer.
+func (fr *FileRenamer) move(mode OutputMode, source, target string) (OutputMode, error) {
+	used, err := transfer(mode, source, target)
+	if err != nil {
+		return used, err
+	}
+	fr.record(source, target, used)
+	return used, nil
+}
+
+// record adds a completed rename to the journal, if enabled.
+func (fr *FileRenamer) record(source, target string, mode OutputMode) {
+	if fr.journal == nil {
 		return
 	}
+	if err := fr.journal.Record(source, target, mode); err != nil {
+		fmt.Printf("⚠️	Failed to record the rename of %s in the journal: %v\n", source, err)
+	}
+}
+
+// skip records a file that is not renamed in the report.
+func (fr *FileRenamer) skip(report *renameReport, source, reason string) {
+	report.skipped = append(report.skipped, reportEntry{File: fr.relativeSource(source), Reason: reason})
+}
+
+// relativeSource returns the path of an original file relative to the input folder.
+func (fr *FileRenamer) relativeSource(source string) string {
+	if relative, err := filepath.Rel(fr.inputFolder, source); err == nil {
+		return relative
+	}
+	return source
+}
+
+// resolveEpisode determines the title and episode label of a file, taking multi-episode files, movies and specials into account.
+// Episode ranges are returned as "first-last", e.g. "1-2" for a file containing episodes 1 and 2.
+func (fr *FileRenamer) resolveEpisode(mkvFile string, matches []identifier.MatchInfo) (resolvedName, error) {
+	if len(matches) == 0 {
+		return resolvedName{}, fmt.Errorf("no episode results found for file: %s", mkvFile)
+	}
+
+	// The episode in the original file name counts as an extra vote
+	release := releasename.Parse(mkvFile)
+	majorityTitle, majorityEpisode, confidence := findMajorityTitleAndEpisode(matches, release.Episode)
+	runnerUp, runnerUpConfidence := findRunnerUpEpisode(matches, release.Episode)
+
+	// Movies are recognised from their format or a missing episode number
+	reference := findMatchWithTitle(matches, majorityTitle)
+	kind := identifier.ClassifyMedia(reference.Format, reference.EpisodeCount, majorityEpisode)
+	if majorityTitle != "" && kind == identifier.Movie {
+		fmt.Printf("🎬	Detected a movie for file: %s\n", mkvFile)
+		return resolvedName{Title: majorityTitle, Kind: kind, Year: reference.Year, Match: reference, Similarity: averageSimilarity(matches, majorityEpisode), Confidence: confidence, RunnerUp: runnerUp, RunnerUpConfidence: runnerUpConfidence}, nil
+	}
+
+	// Single-episode specials may come without an episode number
+	if majorityTitle == "" || (majorityEpisode == "" && kind != identifier.Special) {
+		return resolvedName{}, fmt.Errorf("failed to determine majority episode or title for file: %s", mkvFile)
+	}
+
+	name := resolvedName{
+		Title:      majorityTitle,
+		Episode:    majorityEpisode,
+		Kind:       kind,
+		Year:       reference.Year,
+		Match:      reference,
+		Similarity: averageSimilarity(matches, majorityEpisode),
+		Confidence: confidence,
+
+		RunnerUp:           runnerUp,
+		RunnerUpConfidence: runnerUpConfidence,
+	}
+
+	// Check whether the file contains several episodes or matches episodes at random
+	layout := identifier.AnalyzeEpisodeLayout(matches)
+	switch layout.Kind {
+	case identifier.Unreliable:
+		return resolvedName{}, fmt.Errorf("frames of %s match %d different episode runs at random, skipping as unreliable", mkvFile, len(layout.Runs))
+	case identifier.MultiEpisode:
+		first, last := layout.Runs[0], layout.Runs[len(layout.Runs)-1]
+		fmt.Printf("ℹ️	Detected multiple episodes in %s: episodes %s to %s.\n", mkvFile, first.Episode, last.Episode)
+		name.Episode = first.Episode + "-" + last.Episode
+		return name, nil
+	}
+
+	// Highlight disagreements between the file name and the visual match
+	if release.Episode != "" && release.Episode != majorityEpisode {
+		fmt.Printf("⚠️	The file name of %s suggests episode %s, but the frames matched episode %s.\n", mkvFile, release.Episode, majorityEpisode)
+	}
 
 	// Warn the user if the confidence level is below 90%
-	if confidence < 0.90 {
+	if confidence < identifier.ConfidenceWarningLevel {
 		fmt.Printf("⚠️	The confidence level for episode %s is only %.0f%%. Results may not be reliable.\n", majorityEpisode, confidence*100)
 	}
 
-	// Construct the full path to the original MKV file
-	fullPath := filepath.Join(fr.inputFolder, strings.TrimSpace(mkvFile))
+	// Resolve the season and the canonical franchise title of regular series
+	if kind == identifier.Series && fr.seasons != nil {
+		if season, err := fr.seasons.Resolve(reference.AnilistID); err != nil {
+			fmt.Printf("⚠️	Failed to detect the season for file %s: %v\n", mkvFile, err)
+		} else {
+			name.Title = season.SeriesTitle
+			name.Season = season.Season
+			name.SeriesID = season.RootID
+		}
+	}
 
-	// Check if the file exists before renaming
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		log.Printf("❌	File does not exist: %s\n", fullPath)
-		return
+	// Convert between absolute and seasonal numbering if requested
+	if kind == identifier.Series && fr.scheme != mapping.SchemeSource {
+		fr.applyEpisodeScheme(&name, reference.AnilistID, mkvFile)
 	}
 
-	// Construct the new file name for the original MKV
-	newFileName := constructNewFileName(fullPath, majorityTitle, majorityEpisode)
-	fmt.Println()
-	fmt.Printf("📍	Renaming File:\n")
-	fmt.Printf("➡️	Original:  %s\n", filepath.Base(fullPath))
-	fmt.Printf("➡️	New Name:  %s\n", filepath.Base(newFileName))
-
-	// Prompt user for confirmation
-	if confirmRename() {
-		// Rename the original MKV file
-		err := os.Rename(fullPath, newFileName)
-		if err != nil {
-			fmt.Println()
-			log.Printf("❌	Failed to rename file %s: %v", fullPath, err)
-			fmt.Println()
-			fmt.Println()
-		} else {
-			fmt.Println()
-			fmt.Printf("✅	Successfully renamed file to: %s\n", newFileName)
-			fmt.Println()
-			fmt.Println()
+	// Map specials to their S00 episode number if the specials table knows them
+	if kind == identifier.Special {
+		if number, ok := fr.specials.Lookup(reference.AnilistID, majorityEpisode); ok {
+			name.Episode = strconv.Itoa(number)
+		} else if name.Episode == "" {
+			name.Episode = "1"
 		}
-	} else {
-		fmt.Println()
-		fmt.Printf("⏭️	Skipped renaming for file: %s\n", mkvFile)
-		fmt.Println()
-		fmt.Println()
+		fmt.Printf("ℹ️	Detected a special for file: %s (S00E%s)\n", mkvFile, name.Episode)
 	}
+
+	return name, nil
 }
 
-// findMajorityTitleAndEpisode finds the most frequent title and episode number in the list and calculates the confidence level.
-func findMajorityTitleAndEpisode(matches []identifier.MatchInfo) (string, string, float64) {
-	episodeCount := make(map[string]int)
-	titleCount := make(map[string]int)
+// applyEpisodeScheme converts the episode label of a name into the configured numbering scheme.
+func (fr *FileRenamer) applyEpisodeScheme(name *resolvedName, aniListID int, mkvFile string) {
+	if fr.episodeMap == nil {
+		fmt.Printf("⚠️	No episode mapping loaded, keeping the original numbering for file: %s\n", mkvFile)
+		return
+	}
 
-	for _, match := range matches {
-		// Count episode occurrences
-		episodeCount[match.Episode.String()]++
+	// Convert every part of the label so multi-episode ranges stay intact
+	parts := strings.Split(name.Episode, "-")
+	season := 0
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			fmt.Printf("⚠️	Episode %s of file %s is not numeric and cannot be converted.\n", part, mkvFile)
+			return
+		}
 
-		// Count title occurrences (prioritize English, fallback to Romaji or Native)
-		title := match.TitleEnglish
-		if title == "" {
-			title = match.TitleRomaji
+		episode, ok := fr.episodeMap.FromMatch(aniListID, model.EpisodeNumber{Number: number})
+		if !ok {
+			fmt.Printf("⚠️	No episode mapping found for AniList ID %d episode %s, keeping the original numbering for file: %s\n", aniListID, part, mkvFile)
+			return
+		}
+
+		converted := episode.Absolute
+		if fr.scheme == mapping.SchemeSeasonal {
+			converted = episode.Seasonal
 		}
-		if title == "" {
-			title = match.TitleNative
+		parts[i] = strconv.FormatFloat(converted, 'f', -1, 64)
+		if i == 0 {
+			season = episode.Season
 		}
-		titleCount[title]++
 	}
 
-	// Find the most common episode
-	var majorityEpisode string
-	maxEpisodeCount := 0
-	totalCount := len(matches)
-	for episode, count := range episodeCount {
-		if count > maxEpisodeCount || (count == maxEpisodeCount && episode < majorityEpisode) {
-			majorityEpisode = episode
-			maxEpisodeCount = count
+	name.Episode = strings.Join(parts, "-")
+	if fr.scheme == mapping.SchemeSeasonal {
+		name.Season = season
+	} else {
+		name.Season = 0 // Absolute numbering does not carry a season
+	}
+}
+
+// findMatchWithTitle returns the first match carrying the given display title.
+func findMatchWithTitle(matches []identifier.MatchInfo, title string) identifier.MatchInfo {
+	for _, match := range matches {
+		if match.DisplayTitle() == title {
+			return match
 		}
 	}
+	return matches[0]
+}
+
+// findMajorityTitleAndEpisode finds the most frequent title and episode number in the list and calculates the confidence level.
+// A non-empty filenameEpisode is counted as one extra episode vote, but the confidence is the share of matched frames only.
+func findMajorityTitleAndEpisode(matches []identifier.MatchInfo, filenameEpisode string) (string, string, float64) {
+	titleCount := make(map[string]int)
+	for _, match := range matches {
+		// Count title occurrences (prioritize English, fallback to Romaji or Native)
+		titleCount[match.DisplayTitle()]++
+	}
 
 	// Find the most common title
 	var majorityTitle string
@@ -207,65 +504,157 @@ func findMajorityTitleAndEpisode(matches []identifier.MatchInfo) (string, string
 		}
 	}
 
-	// Calculate confidence as the percentage of the majority episode count over total episodes
-	confidence := float64(maxEpisodeCount) / float64(totalCount)
-	return majorityTitle, majorityEpisode, confidence
+	// Find the most likely episode
+	ranking := rankEpisodes(matches, filenameEpisode)
+	if len(ranking) == 0 {
+		return majorityTitle, "", 0
+	}
+
+	// Calculate confidence as the share of matched frames showing the majority episode
+	confidence := float64(ranking[0].Frames) / float64(len(matches))
+	return majorityTitle, ranking[0].Episode, confidence
 }
 
-// constructNewFileName constructs a new file name with the series title and episode number.
-func constructNewFileName(originalPath, seriesTitle, episode string) string {
-	// Format the episode number
-	if len(episode) == 1 {
-		episode = "0" + episode
+// findRunnerUpEpisode finds the second most likely episode number and its share of the matched frames,
+// ranking the episodes like findMajorityTitleAndEpisode.
+func findRunnerUpEpisode(matches []identifier.MatchInfo, filenameEpisode string) (string, float64) {
+	ranking := rankEpisodes(matches, filenameEpisode)
+	if len(ranking) < 2 {
+		return "", 0
 	}
+	return ranking[1].Episode, float64(ranking[1].Frames) / float64(len(matches))
+}
 
-	// Replace spaces with dots in the series title
-	seriesTitle = strings.ReplaceAll(seriesTitle, " ", ".")
+// episodeVote collects the votes for one episode number
+type episodeVote struct {
+	Episode    string
+	Frames     int     // Number of matched frames showing the episode
+	Filename   bool    // Whether the original file name names the episode
+	Similarity float64 // Summed similarity of the matched frames
+}
 
-	// Remove special characters, only allow alphanumeric characters and dots
-	re := regexp.MustCompile(`[^a-zA-Z0-9.]`)
-	seriesTitle = re.ReplaceAllString(seriesTitle, "")
+// votes returns the number of votes, counting the file name as one extra vote
+func (v episodeVote) votes() int {
+	if v.Filename {
+		return v.Frames + 1
+	}
+	return v.Frames
+}
 
-	// Construct the new file name using series title and episode number
-	ext := filepath.Ext(originalPath)
-	baseDir := filepath.Dir(originalPath)
-	newFileName := fmt.Sprintf("%s.E%s%s", seriesTitle, episode, ext)
+// rankEpisodes orders the episode numbers of the matches from the most to the least likely.
+// Ties go to the episode with more matched frames, so the file name never outvotes the visual match on its own,
+// then to the higher similarity and finally to the lower episode number.
+func rankEpisodes(matches []identifier.MatchInfo, filenameEpisode string) []episodeVote {
+	votes := make(map[string]*episodeVote)
+	vote := func(episode string) *episodeVote {
+		if votes[episode] == nil {
+			votes[episode] = &episodeVote{Episode: episode}
+		}
+		return votes[episode]
+	}
+	for _, match := range matches {
+		v := vote(match.Episode.String())
+		v.Frames++
+		v.Similarity += match.Similarity
+	}
+	if filenameEpisode != "" {
+		vote(filenameEpisode).Filename = true
+	}
 
-	return filepath.Join(baseDir, newFileName)
+	ranking := make([]episodeVote, 0, len(votes))
+	for _, v := range votes {
+		ranking = append(ranking, *v)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		a, b := ranking[i], ranking[j]
+		if a.votes() != b.votes() {
+			return a.votes() > b.votes()
+		}
+		if a.Frames != b.Frames {
+			return a.Frames > b.Frames
+		}
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		return episodeBefore(a.Episode, b.Episode)
+	})
+	return ranking
 }
 
-// confirmRename prompts the user to confirm the renaming action using basic text input.
-func confirmRename() bool {
-	for {
-		fmt.Printf("↪️	Do you want to rename (y/n): ")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-
-		if input == "y" {
-			return true
-		} else if input == "n" {
-			return false
-		} else {
-			fmt.Println("❌	Invalid input. Please type 'y' for yes or 'n' for no.")
+// episodeBefore orders episode numbers numerically, so episode 9 comes before episode 10.
+// Labels that are not numbers are ordered as text after the numbers.
+func episodeBefore(a, b string) bool {
+	numberA, errA := strconv.ParseFloat(a, 64)
+	numberB, errB := strconv.ParseFloat(b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return numberA < numberB
+	case errA == nil || errB == nil:
+		return errA == nil
+	default:
+		return a < b
+	}
+}
+
+// averageSimilarity returns the average similarity of the matches of an episode in percent.
+func averageSimilarity(matches []identifier.MatchInfo, episode string) float64 {
+	var sum float64
+	var count int
+	for _, match := range matches {
+		if match.Episode.String() == episode {
+			sum += match.Similarity
+			count++
 		}
 	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// newFilePath renders the naming template for a file. The rendered path is relative to the output folder,
+// or to the folder of the original file if no output folder is set.
+func (fr *FileRenamer) newFilePath(originalPath string, name resolvedName) (string, error) {
+	data := newNameData(originalPath, name)
+	if fr.idTagForm != "" && data.AniListID != 0 {
+		data.IDTag = fmt.Sprintf(fr.idTagForm, data.AniListID)
+	}
+
+	relative, err := fr.template.Render(data, filepath.Ext(originalPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to name file %s: %v", filepath.Base(originalPath), err)
+	}
+	return filepath.Join(fr.targetFolder(originalPath), relative), nil
+}
+
+// targetFolder returns the folder the rendered name of a file is relative to.
+func (fr *FileRenamer) targetFolder(originalPath string) string {
+	if fr.outputDir != "" {
+		return fr.outputDir
+	}
+	return filepath.Dir(originalPath)
+}
+
+// displayPath returns the new path relative to its target folder, so moves into folders are visible.
+func (fr *FileRenamer) displayPath(originalPath, newPath string) string {
+	if relative, err := filepath.Rel(fr.targetFolder(originalPath), newPath); err == nil {
+		return relative
+	}
+	return newPath
+}
+
+// moveFile renames a file, creating the folders of the new path as needed. Existing files are never replaced.
+func moveFile(oldPath, newPath string) error {
+	_, err := transfer(ModeRename, oldPath, newPath)
+	return err
+}
+
+// confirmRename prompts the user to confirm the renaming action using basic text input.
+func confirmRename() bool {
+	return prompt.YesNo("↪️	Do you want to rename (y/n): ")
 }
 
 // ConfirmBulkRename prompts the user to choose bulk renaming or individual renaming.
 func ConfirmBulkRename() bool {
-	for {
-		fmt.Printf("↪️	Do you want to start Bulkrenamer (y to confirm, n to cancel and go back to individual renaming)? \n")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-
-		if input == "y" {
-			return true
-		} else if input == "n" {
-			return false
-		} else {
-			fmt.Println("❌	Invalid input. Please type 'y' for yes or 'n' for no.")
-		}
-	}
+	return prompt.YesNo("↪️	Do you want to start Bulkrenamer (y to confirm, n to cancel and go back to individual renaming)? \n")
 }
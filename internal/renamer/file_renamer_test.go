@@ -0,0 +1,155 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend"
+	"github.com/WhereIsF1/FumoFinder/internal/extractor"
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"
+	"github.com/WhereIsF1/FumoFinder/internal/model"
+	"github.com/WhereIsF1/FumoFinder/internal/tracemoetest"
+)
+
+// vote creates a match of a title and episode with the given similarity in percent
+func vote(title, episode string, similarity float64) identifier.MatchInfo {
+	return identifier.MatchInfo{TitleEnglish: title, Episode: model.EpisodeNumber{Raw: episode}, Similarity: similarity}
+}
+
+func TestFindMajorityTitleAndEpisode(t *testing.T) {
+	tests := []struct {
+		name               string
+		matches            []identifier.MatchInfo
+		filenameEpisode    string
+		title              string
+		episode            string
+		confidence         float64
+		runnerUp           string
+		runnerUpConfidence float64
+	}{
+		{"unanimous", []identifier.MatchInfo{vote("Show", "5", 95), vote("Show", "5", 94)}, "", "Show", "5", 1, "", 0},
+		{"majority", []identifier.MatchInfo{vote("Show", "5", 95), vote("Show", "5", 94), vote("Show", "6", 90), vote("Show", "5", 93)}, "", "Show", "5", 0.75, "6", 0.25},
+		{"tie goes to the higher similarity", []identifier.MatchInfo{vote("Show", "6", 90), vote("Show", "5", 95)}, "", "Show", "5", 0.5, "6", 0.5},
+		{"tie goes to the lower episode", []identifier.MatchInfo{vote("Show", "10", 90), vote("Show", "9", 90)}, "", "Show", "9", 0.5, "10", 0.5},
+		{"file name breaks a tie", []identifier.MatchInfo{vote("Show", "5", 95), vote("Show", "6", 90)}, "6", "Show", "6", 0.5, "5", 0.5},
+		{"file name does not outvote frames", []identifier.MatchInfo{vote("Show", "5", 95)}, "6", "Show", "5", 1, "6", 0},
+		{"majority title", []identifier.MatchInfo{vote("Other", "1", 90), vote("Show", "5", 95), vote("Show", "5", 95)}, "", "Show", "5", 2.0 / 3, "1", 1.0 / 3},
+		{"title tie ordered by name", []identifier.MatchInfo{vote("B Show", "1", 90), vote("A Show", "1", 90)}, "", "A Show", "1", 1, "", 0},
+		{"no matches", nil, "", "", "", 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, episode, confidence := findMajorityTitleAndEpisode(tt.matches, tt.filenameEpisode)
+			if title != tt.title || episode != tt.episode || confidence != tt.confidence {
+				t.Errorf("got %q episode %q at %.2f, want %q episode %q at %.2f", title, episode, confidence, tt.title, tt.episode, tt.confidence)
+			}
+			runnerUp, runnerUpConfidence := findRunnerUpEpisode(tt.matches, tt.filenameEpisode)
+			if runnerUp != tt.runnerUp || runnerUpConfidence != tt.runnerUpConfidence {
+				t.Errorf("runner-up %q at %.2f, want %q at %.2f", runnerUp, runnerUpConfidence, tt.runnerUp, tt.runnerUpConfidence)
+			}
+		})
+	}
+}
+
+func TestRenameFilesFromIdentifier(t *testing.T) {
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil { // Frames are stored relative to the working directory
+		t.Fatal(err)
+	}
+	defer os.Chdir(previous)
+
+	ffmpegPath, ffprobePath, err := tracemoetest.WriteFFmpegShim(dir, 1440)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracemoetest.WriteVideos("videos", "[G] Show - 05 [1080p].mkv", "[G] Show - 06 [1080p].mkv", "Unknown.mkv"); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, "videos", map[string]int{"[G] Show - 05 [1080p].en.ass": 1})
+
+	server := tracemoetest.NewServer()
+	defer server.Close()
+	server.OnFrame("Show - 05", tracemoetest.Found(tracemoetest.Match(1001, "Show", 5, tracemoetest.AtFrameTime, 0.95)))
+	server.OnFrame("Show - 06", tracemoetest.Found(tracemoetest.Match(1001, "Show", 6, tracemoetest.AtFrameTime, 0.93)))
+
+	frames, err := extractor.NewFrameExtractor(ffmpegPath, ffprobePath, 3).ExtractFrames("videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ei := identifier.NewEpisodeIdentifier(server.Backend(backend.TraceMoeConfig{}), 0, nil)
+	ei.IdentifyEpisodes(frames, 5)
+
+	journalPath := filepath.Join(dir, "rename-journal.jsonl")
+	journal, err := OpenJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := NewFileRenamer("videos")
+	fr.SetRenamePolicy(RenameAuto, identifier.ConfidenceWarningLevel)
+	fr.SetJournal(journal)
+	for _, match := range ei.Matches {
+		fr.AddResult(match)
+	}
+	fr.RenameFiles()
+
+	assertFiles(t, "videos", map[string]bool{
+		"Show.E05.mkv":                 true,
+		"Show.E05.en.ass":              true,
+		"Show.E06.mkv":                 true,
+		"Unknown.mkv":                  true,
+		"[G] Show - 05 [1080p].mkv":    false,
+		"[G] Show - 05 [1080p].en.ass": false,
+		"[G] Show - 06 [1080p].mkv":    false,
+	})
+
+	// The journal reverses the whole run
+	entries, err := LoadJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo, err := OpenJournal(journalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo.UndoRun(entries, LastRun(entries))
+
+	assertFiles(t, "videos", map[string]bool{
+		"[G] Show - 05 [1080p].mkv":    true,
+		"[G] Show - 05 [1080p].en.ass": true,
+		"[G] Show - 06 [1080p].mkv":    true,
+		"Show.E05.mkv":                 false,
+		"Show.E05.en.ass":              false,
+		"Show.E06.mkv":                 false,
+	})
+}
+
+// writeFiles creates files in dir with the given sizes
+func writeFiles(t *testing.T, dir string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertFiles checks which files exist in dir
+func assertFiles(t *testing.T, dir string, exists map[string]bool) {
+	t.Helper()
+	for name, want := range exists {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s exists: %t, want %t", name, err == nil, want)
+		}
+	}
+}
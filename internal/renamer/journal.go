@@ -0,0 +1,246 @@
+package renamer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalEntry records a single rename, so it can be reversed later
+type JournalEntry struct {
+	Time    time.Time `json:"time"`
+	RunID   string    `json:"run_id"`
+	Old     string    `json:"old"`               // Absolute path before the rename
+	New     string    `json:"new"`               // Absolute path after the rename
+	Size    int64     `json:"size"`              // Size of the file after the rename
+	ModTime time.Time `json:"mod_time"`          // Modification time of the file after the rename
+	Mode    string    `json:"mode,omitempty"`    // Output mode, "create" for generated files, "remove" for files deleted by undo (empty for renames)
+	UndoOf  string    `json:"undo_of,omitempty"` // Run reversed by this rename (empty for regular renames)
+}
+
+// Journal modes of entries that are not renames
+const (
+	journalCreate = "create" // File generated by a run, e.g. an NFO file
+	journalRemove = "remove" // Link, copy or generated file deleted by undo
+)
+
+// Journal appends every rename of a run to a JSON lines file
+type Journal struct {
+	mu     sync.Mutex
+	path   string
+	runID  string
+	undoOf string
+}
+
+// DefaultJournalPath returns the journal location in the FumoFinder cache directory.
+func DefaultJournalPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(".", ".fumofinder-cache", "rename-journal.jsonl")
+	}
+	return filepath.Join(cacheDir, "fumofinder", "rename-journal.jsonl")
+}
+
+// OpenJournal opens the journal at path for a new run, using the default location if path is empty.
+func OpenJournal(path string) (*Journal, error) {
+	if path == "" {
+		path = DefaultJournalPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %v", err)
+	}
+	return &Journal{path: path, runID: time.Now().Format("20060102-150405.000")}, nil
+}
+
+// Path returns the location of the journal file.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// RunID returns the ID the renames of this run are recorded under.
+func (j *Journal) RunID() string {
+	return j.runID
+}
+
+// Record appends a completed rename to the journal, together with the output mode used.
+func (j *Journal) Record(oldPath, newPath string, mode OutputMode) error {
+	oldPath, _ = filepath.Abs(oldPath)
+	newPath, _ = filepath.Abs(newPath)
+	info, err := os.Lstat(newPath)
+	if err != nil {
+		return fmt.Errorf("failed to read renamed file: %v", err)
+	}
+
+	entry := JournalEntry{
+		Time:    time.Now(),
+		RunID:   j.runID,
+		Old:     oldPath,
+		New:     newPath,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		UndoOf:  j.undoOf,
+	}
+	if mode != ModeRename {
+		entry.Mode = string(mode)
+	}
+	return j.append(entry)
+}
+
+// RecordCreated appends a file generated by the run to the journal, so undo deletes it again.
+func (j *Journal) RecordCreated(path string) error {
+	path, _ = filepath.Abs(path)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read generated file: %v", err)
+	}
+	return j.append(JournalEntry{Time: time.Now(), RunID: j.runID, New: path, Size: info.Size(), ModTime: info.ModTime(), Mode: journalCreate})
+}
+
+// append writes an entry as a new line of the journal.
+func (j *Journal) append(entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	return nil
+}
+
+// LoadJournal reads all entries of the journal at path, using the default location if path is empty.
+// A missing journal results in no entries.
+func LoadJournal(path string) ([]JournalEntry, error) {
+	if path == "" {
+		path = DefaultJournalPath()
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("invalid journal line %d: %v", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+	return entries, nil
+}
+
+// LastRun returns the ID of the most recent run that renamed files and has not been undone yet.
+func LastRun(entries []JournalEntry) string {
+	undone := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.UndoOf != "" {
+			undone[entry.UndoOf] = true
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].UndoOf == "" && !undone[entries[i].RunID] {
+			return entries[i].RunID
+		}
+	}
+	return ""
+}
+
+// UndoRun reverses the renames of a run in reverse order and records the reversal in the journal.
+// Generated files are deleted, links and copies as long as their original is still in place.
+// Files that were changed, moved or replaced since the rename are left alone.
+func (j *Journal) UndoRun(entries []JournalEntry, runID string) {
+	j.undoOf = runID
+	report := &renameReport{}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.RunID != runID || entry.UndoOf != "" {
+			continue
+		}
+
+		info, err := os.Lstat(entry.New)
+		if err != nil {
+			report.skipped = append(report.skipped, reportEntry{File: entry.New, Reason: "renamed file is missing"})
+			continue
+		}
+		if info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+			report.skipped = append(report.skipped, reportEntry{File: entry.New, Reason: "file changed since the rename"})
+			continue
+		}
+
+		if entry.Mode == journalCreate || OutputMode(entry.Mode).KeepsOriginal() {
+			j.removeCopy(entry, report)
+			continue
+		}
+
+		if original, err := os.Lstat(entry.Old); err == nil && !os.SameFile(original, info) {
+			report.skipped = append(report.skipped, reportEntry{File: entry.New, Reason: "original path is taken by another file"})
+			continue
+		}
+
+		if err := moveFile(entry.New, entry.Old); err != nil {
+			fmt.Printf("❌	Failed to restore file %s: %v\n", entry.Old, err)
+			report.failed = append(report.failed, reportEntry{File: entry.New, Reason: err.Error()})
+			continue
+		}
+		if err := j.Record(entry.New, entry.Old, ModeRename); err != nil {
+			fmt.Printf("⚠️	Failed to record the restored file %s in the journal: %v\n", entry.Old, err)
+		}
+		fmt.Printf("✅	Restored file: %s\n", entry.Old)
+		report.renamed = append(report.renamed, reportEntry{File: entry.New, Target: entry.Old})
+	}
+
+	report.display()
+}
+
+// removeCopy deletes a link, copy or generated file of a run, refusing if the original of a link or copy is gone so no data is lost.
+func (j *Journal) removeCopy(entry JournalEntry, report *renameReport) {
+	if _, err := os.Stat(entry.Old); entry.Mode != journalCreate && err != nil {
+		report.skipped = append(report.skipped, reportEntry{File: entry.New, Reason: "original file is missing, keeping the " + entry.Mode})
+		return
+	}
+	if err := os.RemoveAll(entry.New); err != nil {
+		fmt.Printf("❌	Failed to remove %s: %v\n", entry.New, err)
+		report.failed = append(report.failed, reportEntry{File: entry.New, Reason: err.Error()})
+		return
+	}
+
+	if err := j.append(JournalEntry{Time: time.Now(), RunID: j.runID, Old: entry.New, Mode: journalRemove, UndoOf: j.undoOf}); err != nil {
+		fmt.Printf("⚠️	Failed to record the removal of %s in the journal: %v\n", entry.New, err)
+	}
+	kind := entry.Mode
+	if kind == journalCreate {
+		kind = "generated file"
+	}
+	fmt.Printf("✅	Removed %s: %s\n", kind, entry.New)
+	report.renamed = append(report.renamed, reportEntry{File: entry.New, Target: "removed"})
+}
@@ -0,0 +1,203 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// renameRecorded renames a file in dir and records the rename in the journal
+func renameRecorded(t *testing.T, j *Journal, dir, oldName, newName string, mode OutputMode) {
+	t.Helper()
+	oldPath, newPath := filepath.Join(dir, oldName), filepath.Join(dir, newName)
+	if _, err := transfer(mode, oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(oldPath, newPath, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndoRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]int
+		run    func(t *testing.T, j *Journal, dir string) // Renames of the run, followed by changes made afterwards
+		exists map[string]bool                            // Files expected after the undo
+		kept   bool                                       // Whether nothing was reversed, so the run remains the last run
+	}{
+		{
+			name:  "renames restored",
+			files: map[string]int{"a - 05.mkv": 1, "a - 06.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+				renameRecorded(t, j, dir, "a - 06.mkv", "Show.E06.mkv", ModeRename)
+			},
+			exists: map[string]bool{"a - 05.mkv": true, "a - 06.mkv": true, "Show.E05.mkv": false, "Show.E06.mkv": false},
+		},
+		{
+			name:  "chained renames restored in reverse order",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+				renameRecorded(t, j, dir, "Show.E05.mkv", "Show.S01E05.mkv", ModeRename)
+			},
+			exists: map[string]bool{"a - 05.mkv": true, "Show.E05.mkv": false, "Show.S01E05.mkv": false},
+		},
+		{
+			name:  "changed file left alone",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+				writeFiles(t, dir, map[string]int{"Show.E05.mkv": 3})
+			},
+			exists: map[string]bool{"a - 05.mkv": false, "Show.E05.mkv": true},
+			kept:   true,
+		},
+		{
+			name:  "original path taken",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+				writeFiles(t, dir, map[string]int{"a - 05.mkv": 2})
+			},
+			exists: map[string]bool{"a - 05.mkv": true, "Show.E05.mkv": true},
+			kept:   true,
+		},
+		{
+			name:  "renamed file missing",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+				os.Remove(filepath.Join(dir, "Show.E05.mkv"))
+			},
+			exists: map[string]bool{"a - 05.mkv": false, "Show.E05.mkv": false},
+			kept:   true,
+		},
+		{
+			name:  "copy removed",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeCopy)
+			},
+			exists: map[string]bool{"a - 05.mkv": true, "Show.E05.mkv": false},
+		},
+		{
+			name:  "copy kept without its original",
+			files: map[string]int{"a - 05.mkv": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				renameRecorded(t, j, dir, "a - 05.mkv", "Show.E05.mkv", ModeHardlink)
+				os.Remove(filepath.Join(dir, "a - 05.mkv"))
+			},
+			exists: map[string]bool{"a - 05.mkv": false, "Show.E05.mkv": true},
+			kept:   true,
+		},
+		{
+			name:  "generated file removed",
+			files: map[string]int{"Show.E05.nfo": 1},
+			run: func(t *testing.T, j *Journal, dir string) {
+				if err := j.RecordCreated(filepath.Join(dir, "Show.E05.nfo")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			exists: map[string]bool{"Show.E05.nfo": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			path := filepath.Join(dir, "journal", "rename-journal.jsonl")
+			run, err := OpenJournal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.run(t, run, dir)
+
+			entries, err := LoadJournal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runID := LastRun(entries); runID != run.RunID() {
+				t.Fatalf("last run = %q, want %q", runID, run.RunID())
+			}
+			undo := &Journal{path: path, runID: "undo"}
+			undo.UndoRun(entries, run.RunID())
+
+			assertFiles(t, dir, tt.exists)
+
+			entries, err = LoadJournal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			if tt.kept {
+				want = run.RunID()
+			}
+			if runID := LastRun(entries); runID != want {
+				t.Errorf("last run after undo = %q, want %q", runID, want)
+			}
+		})
+	}
+}
+
+func TestUndoRunOnlyReversesTheGivenRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{"a - 05.mkv": 1, "a - 06.mkv": 1})
+	path := filepath.Join(dir, "rename-journal.jsonl")
+
+	first := &Journal{path: path, runID: "first"}
+	renameRecorded(t, first, dir, "a - 05.mkv", "Show.E05.mkv", ModeRename)
+	second := &Journal{path: path, runID: "second"}
+	renameRecorded(t, second, dir, "a - 06.mkv", "Show.E06.mkv", ModeRename)
+
+	entries, err := LoadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(&Journal{path: path, runID: "undo"}).UndoRun(entries, "first")
+
+	assertFiles(t, dir, map[string]bool{"a - 05.mkv": true, "Show.E05.mkv": false, "a - 06.mkv": false, "Show.E06.mkv": true})
+
+	entries, err = LoadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runID := LastRun(entries); runID != "second" {
+		t.Errorf("last run = %q, want second", runID)
+	}
+}
+
+func TestLoadJournal(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		entries int
+		wantErr bool
+	}{
+		{"entries", `{"run_id": "1", "old": "/a", "new": "/b"}` + "\n\n" + `{"run_id": "2", "old": "/c", "new": "/d"}` + "\n", 2, false},
+		{"invalid line", `{"run_id": "1"}` + "\n" + `{"run_id": ` + "\n", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rename-journal.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := LoadJournal(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadJournal error = %v, want error %t", err, tt.wantErr)
+			}
+			if len(entries) != tt.entries {
+				t.Errorf("got %d entries, want %d", len(entries), tt.entries)
+			}
+		})
+	}
+
+	entries, err := LoadJournal(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil || entries != nil {
+		t.Errorf("missing journal = %v, %v, want no entries", entries, err)
+	}
+}
@@ -0,0 +1,206 @@
+package renamer
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/WhereIsF1/FumoFinder/internal/releasename" // Import the releasename package for the original group and resolution
+)
+
+// DefaultNameTemplate reproduces the classic naming: Title.E05, Title.S02E05, Title.S00E01 and Title (Year) for movies.
+// The extension of the original file is appended to every rendered name.
+const DefaultNameTemplate = `{{.Title}}` +
+	`{{if eq .Kind "movie"}}{{if .Year}} ({{.Year}}){{end}}` +
+	`{{else if eq .Kind "special"}}.S00E{{pad 2 .First}}{{if .Last}}-E{{pad 2 .Last}}{{end}}` +
+	`{{else if .Season}}.S{{pad 2 .Season}}E{{pad 2 .First}}{{if .Last}}-E{{pad 2 .Last}}{{end}}` +
+	`{{else}}.E{{pad 2 .First}}{{if .Last}}-E{{pad 2 .Last}}{{end}}{{end}}`
+
+// NameData holds the fields available in naming templates
+type NameData struct {
+	Title        string  // Series title, the franchise title if seasons are detected
+	TitleRomaji  string  // Romaji title of the matched media
+	TitleEnglish string  // English title of the matched media
+	TitleNative  string  // Native title of the matched media
+	EpisodeTitle string  // Title of the episode (requires --enrich)
+	AniListID    int     // AniList ID of the matched media
+	MalID        int     // MyAnimeList ID of the matched media
+	Kind         string  // series, movie or special
+	Format       string  // AniList format, e.g. TV, MOVIE or OVA
+	Year         int     // Year the media started airing (0 if unknown)
+	Season       int     // Season number (0 if not detected, 0 for specials)
+	Episode      string  // Episode label, "first-last" for multi-episode files
+	First        string  // First episode of the file
+	Last         string  // Last episode of a multi-episode file (empty for single episodes)
+	Similarity   float64 // Average similarity of the matched frames in percent
+	Confidence   float64 // Share of frames agreeing on the episode in percent
+	Group        string  // Release group parsed from the original file name
+	Resolution   string  // Resolution parsed from the original file name
+	CRC          string  // CRC32 parsed from the original file name
+	OriginalName string  // Original file name without extension
+	IDTag        string  // AniList ID tag for media servers, e.g. {anilist-12345} (empty if disabled)
+}
+
+// Sanitizer cleans the text fields of a name before they are inserted into a template
+type Sanitizer struct {
+	Spaces     string         // Replacement for spaces
+	Disallowed *regexp.Regexp // Characters removed from the fields, nil to keep every character
+}
+
+// Built-in sanitisation rules
+var sanitizers = map[string]Sanitizer{
+	"strict": {Spaces: ".", Disallowed: regexp.MustCompile(`[^a-zA-Z0-9.]`)},         // Classic dotted names with ASCII letters and digits only
+	"safe":   {Spaces: " ", Disallowed: regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)}, // Keep spaces and Unicode, remove characters invalid on common file systems
+	"none":   {Spaces: " ", Disallowed: regexp.MustCompile(`[/\\\x00]`)},             // Only remove path separators
+}
+
+// ParseSanitizer returns the sanitisation rules by name: strict, safe or none
+func ParseSanitizer(name string) (Sanitizer, error) {
+	if name == "" {
+		name = "strict"
+	}
+	sanitizer, ok := sanitizers[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Sanitizer{}, fmt.Errorf("unknown sanitisation rules: %s (expected strict, safe or none)", name)
+	}
+	return sanitizer, nil
+}
+
+// Clean applies the sanitisation rules to a field
+func (s Sanitizer) Clean(value string) string {
+	value = strings.ReplaceAll(value, " ", s.Spaces)
+	if s.Disallowed != nil {
+		value = s.Disallowed.ReplaceAllString(value, "")
+	}
+	return strings.TrimSpace(value)
+}
+
+// NameTemplate renders new file names from a text/template. Templates may contain "/" to move files into folders.
+type NameTemplate struct {
+	tmpl      *template.Template
+	sanitizer Sanitizer
+}
+
+// templateFuncs are the helper functions available in naming templates
+var templateFuncs = template.FuncMap{
+	"pad":   padNumber,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"replace": func(old, new, value string) string {
+		return strings.ReplaceAll(value, old, new)
+	},
+	"default": func(fallback, value any) any {
+		if value == nil || value == "" || value == 0 {
+			return fallback
+		}
+		return value
+	},
+}
+
+// ParseNameTemplate parses a naming template, using the default template if text is empty
+func ParseNameTemplate(text string, sanitizer Sanitizer) (*NameTemplate, error) {
+	if text == "" {
+		text = DefaultNameTemplate
+	}
+	tmpl, err := template.New("name").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template: %v", err)
+	}
+	return &NameTemplate{tmpl: tmpl, sanitizer: sanitizer}, nil
+}
+
+// Render renders the relative path of the new file, including the extension of the original file
+func (nt *NameTemplate) Render(data NameData, ext string) (string, error) {
+	// Sanitise the text fields, so titles cannot introduce folders or invalid characters
+	for _, field := range []*string{&data.Title, &data.TitleRomaji, &data.TitleEnglish, &data.TitleNative, &data.EpisodeTitle, &data.Group, &data.Resolution, &data.OriginalName} {
+		*field = nt.sanitizer.Clean(*field)
+	}
+
+	var buf bytes.Buffer
+	if err := nt.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render naming template: %v", err)
+	}
+
+	// Clean every folder level of the rendered path and reject paths leaving the folder
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(buf.String(), "\\", "/"), "/") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			return "", fmt.Errorf("naming template must not leave the folder: %s", buf.String())
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("naming template rendered an empty name")
+	}
+
+	return filepath.Join(parts...) + ext, nil
+}
+
+// newNameData collects the template fields of a resolved name
+func newNameData(originalPath string, name resolvedName) NameData {
+	original := strings.TrimSuffix(filepath.Base(originalPath), filepath.Ext(originalPath))
+	release := releasename.Parse(originalPath)
+	first, last, _ := strings.Cut(name.Episode, "-")
+
+	return NameData{
+		Title:        name.Title,
+		TitleRomaji:  name.Match.TitleRomaji,
+		TitleEnglish: name.Match.TitleEnglish,
+		TitleNative:  name.Match.TitleNative,
+		EpisodeTitle: name.Match.EpisodeTitle,
+		AniListID:    name.Match.AnilistID,
+		MalID:        name.Match.MalID,
+		Kind:         name.Kind.String(),
+		Format:       name.Match.Format,
+		Year:         name.Year,
+		Season:       name.Season,
+		Episode:      name.Episode,
+		First:        first,
+		Last:         last,
+		Similarity:   name.Similarity,
+		Confidence:   name.Confidence * 100,
+		Group:        release.Group,
+		Resolution:   release.Resolution,
+		CRC:          release.CRC,
+		OriginalName: original,
+	}
+}
+
+// padNumber zero-pads the integer part of an episode or season number to the given width,
+// keeping decimals and longer numbers intact, e.g. 5 becomes 05, 12.5 stays 12.5 and 100 stays 100.
+// Episode ranges like 1-2 are padded part by part.
+func padNumber(width int, value any) string {
+	var text string
+	switch v := value.(type) {
+	case int:
+		text = strconv.Itoa(v)
+	case string:
+		text = v
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	parts := strings.Split(text, "-")
+	for i, part := range parts {
+		integer, fraction, _ := strings.Cut(part, ".")
+		if _, err := strconv.Atoi(integer); err != nil {
+			continue // Leave non-numeric episodes as they are
+		}
+		if len(integer) < width {
+			integer = strings.Repeat("0", width-len(integer)) + integer
+		}
+		if fraction != "" {
+			integer += "." + fraction
+		}
+		parts[i] = integer
+	}
+	return strings.Join(parts, "-")
+}
@@ -0,0 +1,96 @@
+package renamer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPadNumber(t *testing.T) {
+	tests := []struct {
+		width int
+		value any
+		want  string
+	}{
+		{2, 5, "05"},
+		{2, "5", "05"},
+		{2, "12", "12"},
+		{2, "100", "100"},
+		{3, "7", "007"},
+		{2, "12.5", "12.5"},
+		{2, "5.5", "05.5"},
+		{2, "1-2", "01-02"},
+		{2, "9-10", "09-10"},
+		{2, "SP1", "SP1"},
+		{2, "", ""},
+		{2, 0, "00"},
+	}
+
+	for _, tt := range tests {
+		if got := padNumber(tt.width, tt.value); got != tt.want {
+			t.Errorf("padNumber(%d, %v) = %q, want %q", tt.width, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNameTemplateRender(t *testing.T) {
+	series := NameData{Title: "Show Title", Kind: "series", Episode: "5", First: "5", AniListID: 1001, Group: "Sub Group", Resolution: "1080p"}
+	season := NameData{Title: "Show Title", Kind: "series", Season: 2, Episode: "5", First: "5"}
+	multi := NameData{Title: "Show Title", Kind: "series", Episode: "1-2", First: "1", Last: "2"}
+	special := NameData{Title: "Show Title", Kind: "special", Episode: "1", First: "1"}
+	movie := NameData{Title: "Movie: The Title", Kind: "movie", Year: 2019}
+
+	tests := []struct {
+		name      string
+		template  string
+		sanitizer string
+		data      NameData
+		want      string
+		wantErr   bool
+	}{
+		{"default series", "", "strict", series, "Show.Title.E05.mkv", false},
+		{"default season", "", "strict", season, "Show.Title.S02E05.mkv", false},
+		{"default multi-episode", "", "strict", multi, "Show.Title.E01-E02.mkv", false},
+		{"default special", "", "strict", special, "Show.Title.S00E01.mkv", false},
+		{"default movie", "", "strict", movie, "Movie.The.Title (2019).mkv", false},
+		{"decimal episode", "", "strict", NameData{Title: "Show", Kind: "series", Episode: "12.5", First: "12.5"}, "Show.E12.5.mkv", false},
+		{"safe keeps spaces", "{{.Title}} - {{pad 2 .First}}", "safe", series, "Show Title - 05.mkv", false},
+		{"safe removes invalid characters", "{{.Title}}", "safe", movie, "Movie The Title.mkv", false},
+		{"fields and helpers", "[{{.Group}}] {{upper .Title}} - {{pad 3 .Episode}} [{{.Resolution}}] {{.AniListID}}", "safe", series, "[Sub Group] SHOW TITLE - 005 [1080p] 1001.mkv", false},
+		{"default helper", "{{.Title}} {{default \"unknown\" .Group}}", "safe", season, "Show Title unknown.mkv", false},
+		{"folders", "{{.Title}}/Season {{pad 2 .Season}}/{{.Title}} - {{pad 2 .First}}", "safe", season, filepath.Join("Show Title", "Season 02", "Show Title - 05.mkv"), false},
+		{"title cannot add folders", "{{.Title}}", "none", NameData{Title: "AC/DC"}, "ACDC.mkv", false},
+		{"leaving the folder", "../{{.Title}}", "safe", series, "", true},
+		{"empty name", "{{.Group}}", "safe", season, "", true},
+		{"unknown field", "{{.Missing}}", "safe", series, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sanitizer, err := ParseSanitizer(tt.sanitizer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := ParseNameTemplate(tt.template, sanitizer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tmpl.Render(tt.data, ".mkv")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNameTemplateRejectsInvalidTemplates(t *testing.T) {
+	if _, err := ParseNameTemplate("{{.Title", sanitizers["strict"]); err == nil {
+		t.Error("expected an error for an unterminated action")
+	}
+	if _, err := ParseSanitizer("fancy"); err == nil {
+		t.Error("expected an error for unknown sanitisation rules")
+	}
+}
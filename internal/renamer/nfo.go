@@ -0,0 +1,200 @@
+package renamer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/WhereIsF1/FumoFinder/internal/identifier" // Import the identifier package for media kinds
+)
+
+// nfoMarker identifies NFO files written by FumoFinder, which may be replaced by later runs
+const nfoMarker = "<!-- Generated by FumoFinder -->"
+
+// nfoUniqueID is an ID of the media in an external database
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	ID      int    `xml:",chardata"`
+}
+
+// nfoEpisode is the episodedetails NFO of a single episode
+type nfoEpisode struct {
+	XMLName   xml.Name      `xml:"episodedetails"`
+	Title     string        `xml:"title"`
+	ShowTitle string        `xml:"showtitle"`
+	Season    int           `xml:"season"`
+	Episode   string        `xml:"episode"`
+	UniqueIDs []nfoUniqueID `xml:"uniqueid"`
+}
+
+// nfoShow is the tvshow NFO in the folder of a show
+type nfoShow struct {
+	XMLName       xml.Name      `xml:"tvshow"`
+	Title         string        `xml:"title"`
+	OriginalTitle string        `xml:"originaltitle,omitempty"`
+	Year          int           `xml:"year,omitempty"`
+	UniqueIDs     []nfoUniqueID `xml:"uniqueid"`
+}
+
+// SetNFO enables episodedetails NFO files next to the renamed videos and tvshow.nfo files in their show folders.
+func (fr *FileRenamer) SetNFO(enabled bool) {
+	fr.nfo = enabled
+}
+
+// writeNFOFiles writes the episode NFO of a renamed video and the tvshow.nfo of its show folder.
+// Movies are left to the media server, and targets without a folder of their own get no tvshow.nfo,
+// as it would describe every show in the shared folder.
+func (fr *FileRenamer) writeNFOFiles(rename plannedRename) {
+	if rename.Name.Kind == identifier.Movie {
+		return
+	}
+
+	episodePath := strings.TrimSuffix(rename.Target, filepath.Ext(rename.Target)) + ".nfo"
+	fr.writeNFO(episodePath, episodeNFO(rename.Name)...)
+
+	folder, ok := showFolder(fr.targetFolder(rename.Source), rename.Target)
+	if !ok {
+		return
+	}
+	showPath := filepath.Join(folder, "tvshow.nfo")
+	if fr.nfoShows == nil {
+		fr.nfoShows = make(map[string]bool)
+	}
+	if !fr.nfoShows[showPath] {
+		fr.nfoShows[showPath] = true
+		fr.writeNFO(showPath, showNFO(rename.Name))
+	}
+}
+
+// writeNFO writes the XML documents into an NFO file and records new files in the journal.
+// NFO files not written by FumoFinder, e.g. from a scraper or a renamed sidecar, are kept.
+func (fr *FileRenamer) writeNFO(path string, documents ...any) {
+	existing, err := os.ReadFile(path)
+	if err == nil && !bytes.Contains(existing, []byte(nfoMarker)) {
+		fmt.Printf("ℹ️	Keeping the existing %s\n", filepath.Base(path))
+		return
+	}
+	created := err != nil
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(nfoMarker + "\n")
+	for _, document := range documents {
+		data, err := xml.MarshalIndent(document, "", "  ")
+		if err != nil {
+			fmt.Printf("⚠️	Failed to write %s: %v\n", filepath.Base(path), err)
+			return
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("⚠️	Failed to write %s: %v\n", filepath.Base(path), err)
+		return
+	}
+	// Replaced NFO files of earlier runs stay when this run is undone
+	if fr.journal != nil && created {
+		if err := fr.journal.RecordCreated(path); err != nil {
+			fmt.Printf("⚠️	Failed to record %s in the journal: %v\n", filepath.Base(path), err)
+		}
+	}
+	fmt.Printf("📄	Wrote %s\n", filepath.Base(path))
+}
+
+// episodeNFO builds the episodedetails of a file, one per episode for multi-episode files.
+func episodeNFO(name resolvedName) []any {
+	season := 1 // Series without detected season are placed in Season 01 like the media server presets do
+	if name.Kind == identifier.Special {
+		season = 0
+	} else if name.Season > 0 {
+		season = name.Season
+	}
+
+	var ids []nfoUniqueID
+	if name.Match.AnilistID != 0 {
+		ids = append(ids, nfoUniqueID{Type: "anilist", Default: true, ID: name.Match.AnilistID})
+	}
+	if name.Match.MalID != 0 {
+		ids = append(ids, nfoUniqueID{Type: "mal", ID: name.Match.MalID})
+	}
+
+	var documents []any
+	for _, episode := range expandEpisodes(name.Episode) {
+		title := "Episode " + episode
+		if name.Match.EpisodeTitle != "" && len(documents) == 0 {
+			title = name.Match.EpisodeTitle
+		}
+		documents = append(documents, nfoEpisode{
+			Title:     title,
+			ShowTitle: name.Title,
+			Season:    season,
+			Episode:   episode,
+			UniqueIDs: ids,
+		})
+	}
+	return documents
+}
+
+// showNFO builds the tvshow NFO of the show a file belongs to.
+// With season detection, the show is identified by the first season of the franchise.
+func showNFO(name resolvedName) nfoShow {
+	show := nfoShow{
+		Title:         name.Title,
+		OriginalTitle: name.Match.TitleNative,
+		Year:          name.Year,
+	}
+
+	switch {
+	case name.SeriesID != 0 && name.SeriesID != name.Match.AnilistID:
+		// Titles and year of a later season do not describe the whole show
+		show.UniqueIDs = []nfoUniqueID{{Type: "anilist", Default: true, ID: name.SeriesID}}
+		show.OriginalTitle, show.Year = "", 0
+	case name.Match.AnilistID != 0:
+		show.UniqueIDs = []nfoUniqueID{{Type: "anilist", Default: true, ID: name.Match.AnilistID}}
+		if name.Match.MalID != 0 {
+			show.UniqueIDs = append(show.UniqueIDs, nfoUniqueID{Type: "mal", ID: name.Match.MalID})
+		}
+	}
+	return show
+}
+
+// expandEpisodes lists the episodes of a label, e.g. "1-3" becomes 1, 2 and 3. Non-numeric ranges are kept as they are.
+func expandEpisodes(label string) []string {
+	first, last, ok := strings.Cut(label, "-")
+	if !ok {
+		return []string{label}
+	}
+	from, errFrom := strconv.Atoi(first)
+	to, errTo := strconv.Atoi(last)
+	if errFrom != nil || errTo != nil || to < from {
+		return []string{label}
+	}
+
+	var episodes []string
+	for episode := from; episode <= to; episode++ {
+		episodes = append(episodes, strconv.Itoa(episode))
+	}
+	return episodes
+}
+
+// showFolder returns the folder of the show a video belongs to: the parent of a "Season xx" or "Specials" folder,
+// or the folder of the video itself. It reports false if that is not a folder below root, the target folder of the run.
+func showFolder(root, videoPath string) (string, bool) {
+	folder := filepath.Dir(videoPath)
+	base := filepath.Base(folder)
+	if strings.HasPrefix(base, "Season ") || strings.EqualFold(base, "Specials") {
+		folder = filepath.Dir(folder)
+	}
+
+	relative, err := filepath.Rel(root, folder)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return folder, true
+}
@@ -0,0 +1,88 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/WhereIsF1/FumoFinder/internal/identifier"
+)
+
+func TestShowFolder(t *testing.T) {
+	root := filepath.Join("library", "anime")
+
+	tests := []struct {
+		name   string
+		target string
+		folder string // Empty if no tvshow.nfo should be written
+	}{
+		{"season folder", filepath.Join(root, "Show (2023)", "Season 01", "Show - S01E05.mkv"), filepath.Join(root, "Show (2023)")},
+		{"specials folder", filepath.Join(root, "Show (2023)", "Specials", "Show - S00E01.mkv"), filepath.Join(root, "Show (2023)")},
+		{"show folder without seasons", filepath.Join(root, "Show", "Show - 05.mkv"), filepath.Join(root, "Show")},
+		{"flat", filepath.Join(root, "Show.E05.mkv"), ""},
+		{"season folder in the root", filepath.Join(root, "Season 01", "Show - S01E05.mkv"), ""},
+		{"outside the root", filepath.Join("library", "Show.E05.mkv"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder, ok := showFolder(root, tt.target)
+			if ok != (tt.folder != "") || folder != tt.folder {
+				t.Errorf("showFolder(%q) = %q, %v, want %q", tt.target, folder, ok, tt.folder)
+			}
+		})
+	}
+}
+
+func TestWriteNFOFilesPerShowFolder(t *testing.T) {
+	input := t.TempDir()
+	output := t.TempDir()
+
+	fr := NewFileRenamer(input)
+	fr.SetOutputFolder(output)
+	fr.SetNFO(true)
+
+	episode := func(title string, anilistID int, target string) plannedRename {
+		match := identifier.MatchInfo{AnilistID: anilistID, Synonyms: []string{title + " alias"}}
+		return plannedRename{
+			Source: filepath.Join(input, title+".mkv"),
+			Target: filepath.Join(output, target),
+			Name:   resolvedName{Title: title, Episode: "5", Kind: identifier.Series, Match: match},
+		}
+	}
+	renames := []plannedRename{
+		episode("Show", 1001, filepath.Join("Show", "Season 01", "Show - S01E05.mkv")),
+		episode("Show", 1001, filepath.Join("Show", "Season 01", "Show - S01E06.mkv")),
+		episode("Other", 2002, filepath.Join("Other", "Season 01", "Other - S01E05.mkv")),
+		episode("Flat", 3003, "Flat.E05.mkv"),
+	}
+	for _, rename := range renames {
+		if err := os.MkdirAll(filepath.Dir(rename.Target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		fr.writeNFOFiles(rename)
+	}
+
+	for _, show := range []struct {
+		folder string
+		id     string
+	}{{"Show", "1001"}, {"Other", "2002"}} {
+		data, err := os.ReadFile(filepath.Join(output, show.folder, "tvshow.nfo"))
+		if err != nil {
+			t.Fatalf("tvshow.nfo of %s: %v", show.folder, err)
+		}
+		if !strings.Contains(string(data), "<title>"+show.folder+"</title>") || !strings.Contains(string(data), ">"+show.id+"</uniqueid>") {
+			t.Errorf("tvshow.nfo of %s describes another show:\n%s", show.folder, data)
+		}
+		if strings.Contains(string(data), "<synonym>") {
+			t.Errorf("tvshow.nfo of %s lists synonyms:\n%s", show.folder, data)
+		}
+	}
+
+	assertFiles(t, output, map[string]bool{
+		"tvshow.nfo":   false,
+		"Flat.E05.nfo": true,
+		filepath.Join("Show", "Season 01", "Show - S01E06.nfo"): true,
+	})
+}
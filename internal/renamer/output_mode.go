@@ -0,0 +1,153 @@
+package renamer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OutputMode decides how a file gets its new name
+type OutputMode string
+
+const (
+	ModeRename   OutputMode = "rename"   // Rename the file in place, or below the output folder
+	ModeMove     OutputMode = "move"     // Move the file into the library root, copying it across file systems
+	ModeHardlink OutputMode = "hardlink" // Keep the original and hardlink it, copying it across file systems
+	ModeSymlink  OutputMode = "symlink"  // Keep the original and point a symlink at it
+	ModeCopy     OutputMode = "copy"     // Keep the original and copy it
+)
+
+// ParseOutputMode parses an output mode name
+func ParseOutputMode(name string) (OutputMode, error) {
+	switch mode := OutputMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case ModeRename, ModeMove, ModeHardlink, ModeSymlink, ModeCopy:
+		return mode, nil
+	case "":
+		return ModeRename, nil
+	default:
+		return "", fmt.Errorf("unknown output mode: %s (expected rename, move, hardlink, symlink or copy)", name)
+	}
+}
+
+// KeepsOriginal reports whether the original file stays in place, e.g. for seeding torrents.
+func (m OutputMode) KeepsOriginal() bool {
+	return m == ModeHardlink || m == ModeSymlink || m == ModeCopy
+}
+
+// verb describes the mode in messages, e.g. "hardlinked".
+func (m OutputMode) verb() string {
+	switch m {
+	case ModeMove:
+		return "moved"
+	case ModeHardlink:
+		return "hardlinked"
+	case ModeSymlink:
+		return "symlinked"
+	case ModeCopy:
+		return "copied"
+	default:
+		return "renamed"
+	}
+}
+
+// SetOutputMode sets how files get their new names.
+func (fr *FileRenamer) SetOutputMode(mode OutputMode) {
+	fr.mode = mode
+}
+
+// transfer gives a file or folder its new name using the output mode and returns the mode actually used,
+// which differs from the requested one when hardlinks or moves fall back to copying across file systems.
+// Existing files are never replaced.
+func transfer(mode OutputMode, source, target string) (OutputMode, error) {
+	if targetInfo, err := os.Lstat(target); err == nil {
+		// Case-insensitive file systems report the new name of a case-only rename as taken by the file itself
+		sourceInfo, err := os.Lstat(source)
+		if err != nil || !os.SameFile(sourceInfo, targetInfo) || mode.KeepsOriginal() {
+			return mode, fmt.Errorf("%s already exists", target)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return mode, fmt.Errorf("failed to create folder: %v", err)
+	}
+
+	switch mode {
+	case ModeSymlink:
+		absolute, err := filepath.Abs(source)
+		if err != nil {
+			return mode, err
+		}
+		return mode, os.Symlink(absolute, target)
+	case ModeCopy:
+		return mode, copyPath(source, target)
+	case ModeHardlink:
+		info, err := os.Stat(source)
+		if err != nil {
+			return mode, err
+		}
+		if !info.IsDir() {
+			if err := os.Link(source, target); err == nil {
+				return mode, nil
+			} else if !isCrossDevice(err) {
+				return mode, err
+			}
+		}
+		// Folders cannot be hardlinked and links cannot cross file systems
+		return ModeCopy, copyPath(source, target)
+	default:
+		err := os.Rename(source, target)
+		if err == nil || !isCrossDevice(err) {
+			return mode, err
+		}
+		// Moves across file systems copy the file and remove the original afterwards
+		if err := copyPath(source, target); err != nil {
+			os.RemoveAll(target)
+			return mode, err
+		}
+		return mode, os.RemoveAll(source)
+	}
+}
+
+// copyPath copies a file or a folder tree, keeping permissions and modification times.
+func copyPath(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyPath(filepath.Join(source, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %v", source, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,221 @@
+package renamer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanEntry is a single rename of a plan file
+type PlanEntry struct {
+	Source             string    `json:"source"`               // Absolute path of the original file
+	Target             string    `json:"target"`               // Absolute path of the new file, may be edited by hand
+	Title              string    `json:"title"`                // Chosen title
+	Episode            string    `json:"episode"`              // Chosen episode label
+	Confidence         float64   `json:"confidence"`           // Share of frames agreeing on the episode
+	RunnerUp           string    `json:"runner_up"`            // Second most voted episode (empty if all frames agree)
+	RunnerUpConfidence float64   `json:"runner_up_confidence"` // Share of frames voting for the runner-up episode
+	Size               int64     `json:"size"`                 // Size of the original file when the plan was written
+	ModTime            time.Time `json:"mod_time"`             // Modification time of the original file when the plan was written
+}
+
+// Plan is a list of renames written by a dry run and carried out later by apply
+type Plan struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Entries   []PlanEntry `json:"entries"`
+}
+
+// planColumns is the header of CSV plan files
+var planColumns = []string{"source", "target", "title", "episode", "confidence", "runner_up", "runner_up_confidence", "size", "mod_time"}
+
+// WritePlan plans the renames of all identified files and writes them to a plan file without renaming anything.
+// Plans ending in .csv are written as CSV, all others as JSON.
+func (fr *FileRenamer) WritePlan(path string) error {
+	planned, report := fr.planRenames()
+
+	plan := &Plan{CreatedAt: time.Now()}
+	for _, rename := range planned {
+		entry, err := newPlanEntry(rename)
+		if err != nil {
+			fr.skip(report, rename.Source, err.Error())
+			continue
+		}
+		plan.Entries = append(plan.Entries, entry)
+	}
+
+	if err := SavePlan(path, plan); err != nil {
+		return err
+	}
+	fr.displayPlan(planned)
+	fmt.Printf("📝	Wrote a rename plan with %d files to %s. Run FumoFinder apply %s to rename them.\n", len(plan.Entries), path, path)
+	if len(report.skipped) > 0 {
+		report.display() // Explain why files are missing from the plan
+	}
+	return nil
+}
+
+// newPlanEntry records a planned rename together with the size and modification time of its source
+func newPlanEntry(rename plannedRename) (PlanEntry, error) {
+	source, err := filepath.Abs(rename.Source)
+	if err != nil {
+		return PlanEntry{}, err
+	}
+	target, err := filepath.Abs(rename.Target)
+	if err != nil {
+		return PlanEntry{}, err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return PlanEntry{}, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	return PlanEntry{
+		Source:             source,
+		Target:             target,
+		Title:              rename.Name.Title,
+		Episode:            rename.Name.Episode,
+		Confidence:         rename.Name.Confidence,
+		RunnerUp:           rename.Name.RunnerUp,
+		RunnerUpConfidence: rename.Name.RunnerUpConfidence,
+		Size:               info.Size(),
+		ModTime:            info.ModTime(),
+	}, nil
+}
+
+// SavePlan writes a plan as CSV if the path ends in .csv, and as JSON otherwise
+func SavePlan(path string, plan *Plan) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create plan file: %v", err)
+	}
+	defer file.Close()
+
+	if !isCSVPlan(path) {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plan); err != nil {
+			return fmt.Errorf("failed to write plan file: %v", err)
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(planColumns)
+	for _, entry := range plan.Entries {
+		writer.Write([]string{
+			entry.Source,
+			entry.Target,
+			entry.Title,
+			entry.Episode,
+			strconv.FormatFloat(entry.Confidence, 'f', 4, 64),
+			entry.RunnerUp,
+			strconv.FormatFloat(entry.RunnerUpConfidence, 'f', 4, 64),
+			strconv.FormatInt(entry.Size, 10),
+			entry.ModTime.Format(time.RFC3339Nano),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write plan file: %v", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan written by SavePlan, possibly edited by hand
+func LoadPlan(path string) (*Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plan file: %v", err)
+	}
+	defer file.Close()
+
+	if !isCSVPlan(path) {
+		var plan Plan
+		if err := json.NewDecoder(file).Decode(&plan); err != nil {
+			return nil, fmt.Errorf("failed to parse plan file: %v", err)
+		}
+		return &plan, nil
+	}
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %v", err)
+	}
+
+	plan := &Plan{}
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == planColumns[0] {
+			continue // Skip the header
+		}
+		if len(record) != len(planColumns) {
+			return nil, fmt.Errorf("invalid plan file line %d: expected %d columns, got %d", i+1, len(planColumns), len(record))
+		}
+
+		entry := PlanEntry{Source: record[0], Target: record[1], Title: record[2], Episode: record[3], RunnerUp: record[5]}
+		entry.Confidence, _ = strconv.ParseFloat(record[4], 64)
+		entry.RunnerUpConfidence, _ = strconv.ParseFloat(record[6], 64)
+		if entry.Size, err = strconv.ParseInt(record[7], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid size on plan file line %d: %v", i+1, err)
+		}
+		if entry.ModTime, err = time.Parse(time.RFC3339Nano, record[8]); err != nil {
+			return nil, fmt.Errorf("invalid modification time on plan file line %d: %v", i+1, err)
+		}
+		plan.Entries = append(plan.Entries, entry)
+	}
+	return plan, nil
+}
+
+// isCSVPlan reports whether a plan file is stored as CSV
+func isCSVPlan(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// ApplyPlan carries out the renames of a plan, skipping files that changed since the plan was written.
+// Entries whose target equals their source are left alone, so renames can be removed from a plan by editing the target.
+func (fr *FileRenamer) ApplyPlan(plan *Plan) {
+	report := &renameReport{}
+
+	var renames []plannedRename
+	for _, entry := range plan.Entries {
+		if entry.Target == "" || filepath.Clean(entry.Target) == filepath.Clean(entry.Source) {
+			fr.skip(report, entry.Source, "target unchanged")
+			continue
+		}
+		if err := verifySource(entry); err != nil {
+			fmt.Printf("❌	%v\n", err)
+			fr.skip(report, entry.Source, err.Error())
+			continue
+		}
+		renames = append(renames, plannedRename{
+			Source: entry.Source,
+			Target: entry.Target,
+			Name: resolvedName{
+				Title:              entry.Title,
+				Episode:            entry.Episode,
+				Confidence:         entry.Confidence,
+				RunnerUp:           entry.RunnerUp,
+				RunnerUpConfidence: entry.RunnerUpConfidence,
+			},
+		})
+	}
+
+	fr.executeRenames(fr.attachSidecars(fr.resolveCollisions(renames, report), report), report)
+	report.display()
+}
+
+// verifySource checks that the source of a plan entry still has the size and modification time it had when planned
+func verifySource(entry PlanEntry) error {
+	info, err := os.Stat(entry.Source)
+	if err != nil {
+		return fmt.Errorf("source file is missing: %s", entry.Source)
+	}
+	if info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+		return fmt.Errorf("source file changed since planning: %s", entry.Source)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadPlan(t *testing.T) {
+	modTime := time.Date(2024, 4, 1, 12, 30, 15, 123456789, time.UTC)
+	plan := &Plan{
+		CreatedAt: modTime,
+		Entries: []PlanEntry{
+			{Source: "/videos/[G] Show - 05.mkv", Target: "/videos/Show.E05.mkv", Title: "Show", Episode: "5", Confidence: 1, Size: 1024, ModTime: modTime},
+			{Source: "/videos/Show, \"The\" - 06.mkv", Target: "/videos/Show.E06.mkv", Title: "Show", Episode: "6", Confidence: 0.75, RunnerUp: "7", RunnerUpConfidence: 0.25, Size: 2048, ModTime: modTime},
+		},
+	}
+
+	for _, name := range []string{"plan.json", "plan.csv", "plan.CSV"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := SavePlan(path, plan); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadPlan(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertEntries(t, loaded.Entries, plan.Entries)
+		})
+	}
+}
+
+func TestLoadPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		entries int
+		wantErr bool
+	}{
+		{"csv without header", "plan.csv", "/a.mkv,/b.mkv,Show,5,1.0000,,0.0000,10,2024-04-01T12:30:15Z\n", 1, false},
+		{"csv with header only", "plan.csv", "source,target,title,episode,confidence,runner_up,runner_up_confidence,size,mod_time\n", 0, false},
+		{"csv missing columns", "plan.csv", "/a.mkv,/b.mkv,Show\n", 0, true},
+		{"csv invalid size", "plan.csv", "/a.mkv,/b.mkv,Show,5,1,,0,big,2024-04-01T12:30:15Z\n", 0, true},
+		{"csv invalid time", "plan.csv", "/a.mkv,/b.mkv,Show,5,1,,0,10,yesterday\n", 0, true},
+		{"json", "plan.json", `{"entries": [{"source": "/a.mkv", "target": "/b.mkv"}]}`, 1, false},
+		{"json truncated", "plan.json", `{"entries": [`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			plan, err := LoadPlan(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPlan error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && len(plan.Entries) != tt.entries {
+				t.Errorf("got %d entries, want %d", len(plan.Entries), tt.entries)
+			}
+		})
+	}
+
+	if _, err := LoadPlan(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing plan file")
+	}
+}
+
+func TestApplyPlanSkipsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]int{"a - 05.mkv": 1, "a - 06.mkv": 1, "a - 07.mkv": 1})
+
+	var entries []PlanEntry
+	for _, rename := range [][2]string{{"a - 05.mkv", "Show.E05.mkv"}, {"a - 06.mkv", "Show.E06.mkv"}, {"a - 07.mkv", "a - 07.mkv"}} {
+		entry, err := newPlanEntry(plannedRename{Source: filepath.Join(dir, rename[0]), Target: filepath.Join(dir, rename[1])})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a - 06.mkv"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	NewFileRenamer(dir).ApplyPlan(&Plan{Entries: entries})
+
+	assertFiles(t, dir, map[string]bool{"Show.E05.mkv": true, "a - 05.mkv": false, "a - 06.mkv": true, "Show.E06.mkv": false, "a - 07.mkv": true})
+}
+
+// assertEntries compares plan entries, comparing modification times by instant
+func assertEntries(t *testing.T, got, want []PlanEntry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if !got[i].ModTime.Equal(want[i].ModTime) {
+			t.Errorf("entry %d modification time = %s, want %s", i, got[i].ModTime, want[i].ModTime)
+		}
+		g, w := got[i], want[i]
+		g.ModTime, w.ModTime = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("entry %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package renamer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultIDTagForm is the format of AniList ID tags for templates and presets without their own format
+const DefaultIDTagForm = "{anilist-%d}"
+
+// Preset is a built-in naming scheme for a media server, generating the folder tree and the file name
+type Preset struct {
+	Name      string // Name of the preset as used on the command line
+	Template  string // Naming template, relative to the output folder
+	Sanitize  string // Sanitisation rules for the text fields
+	IDTagForm string // Format of the AniList ID tag in folder names, e.g. "[anilistid-%d]" (empty if the media server reads no AniList ID tags)
+}
+
+// mediaServerTemplate builds the Show (Year)/Season 01/Show - S01E05 layout shared by Plex, Jellyfin and Kodi.
+// Movies get their own folder, specials are placed in Season 00 and series without detected season in Season 01.
+const mediaServerTemplate = `{{$show := .Title}}{{if .Year}}{{$show = printf "%s (%d)" .Title .Year}}{{end}}` +
+	`{{if eq .Kind "movie"}}{{$show}}{{with .IDTag}} {{.}}{{end}}/{{$show}}` +
+	`{{else}}{{$season := or .Season 1}}{{if eq .Kind "special"}}{{$season = 0}}{{end}}` +
+	`{{$show}}{{with .IDTag}} {{.}}{{end}}/Season {{pad 2 $season}}/` +
+	`{{.Title}} - S{{pad 2 $season}}E{{pad 2 .First}}{{if .Last}}-E{{pad 2 .Last}}{{end}}{{end}}`
+
+// presets holds the built-in presets by name
+var presets = map[string]Preset{
+	// Plex only reads TVDB, TMDB and IMDb folder tags and Kodi none at all, so their folders are not tagged
+	"plex": {
+		Name:     "plex",
+		Template: mediaServerTemplate,
+		Sanitize: "safe",
+	},
+	"jellyfin": {
+		Name:      "jellyfin",
+		Template:  mediaServerTemplate,
+		Sanitize:  "safe",
+		IDTagForm: "[anilistid-%d]",
+	},
+	"kodi": {
+		Name:     "kodi",
+		Template: mediaServerTemplate,
+		Sanitize: "safe",
+	},
+	"scene": {
+		Name: "scene",
+		Template: `{{.Title}}{{if eq .Kind "movie"}}{{if .Year}}.{{.Year}}{{end}}` +
+			`{{else}}{{$season := or .Season 1}}{{if eq .Kind "special"}}{{$season = 0}}{{end}}` +
+			`.S{{pad 2 $season}}E{{pad 2 .First}}{{if .Last}}-E{{pad 2 .Last}}{{end}}{{end}}`,
+		Sanitize: "strict",
+	},
+}
+
+// LookupPreset returns a built-in preset by name: plex, jellyfin, kodi or scene
+func LookupPreset(name string) (Preset, error) {
+	preset, ok := presets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown naming preset: %s (expected %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return preset, nil
+}
+
+// PresetNames returns the names of the built-in presets
+func PresetNames() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+package renamer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenamePolicy decides which of the planned renames are carried out
+type RenamePolicy string
+
+const (
+	RenameNever           RenamePolicy = "never"             // Only show the planned renames
+	RenameAsk             RenamePolicy = "ask"               // Ask for confirmation in bulk or file by file
+	RenameAuto            RenamePolicy = "auto"              // Rename every identified file without asking
+	RenameAutoIfConfident RenamePolicy = "auto-if-confident" // Rename files whose confidence reaches the threshold without asking
+)
+
+// ParseRenamePolicy parses a rename policy name
+func ParseRenamePolicy(name string) (RenamePolicy, error) {
+	switch policy := RenamePolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case RenameNever, RenameAsk, RenameAuto, RenameAutoIfConfident:
+		return policy, nil
+	case "":
+		return RenameAsk, nil
+	default:
+		return "", fmt.Errorf("unknown rename policy: %s (expected never, ask, auto or auto-if-confident)", name)
+	}
+}
+
+// plannedRename is a single rename of the plan, built before anything is touched on disk
+type plannedRename struct {
+	Source string       // Full path of the original file
+	Target string       // Full path of the new file
+	Name   resolvedName // Resolved title and episode the target was rendered from
+
+	Sidecars  []sidecarRename // Subtitles, NFO files, thumbnails and font folders moving along with the video
+	Displaced bool            // Existing file moved out of the way of a better one, always moved regardless of the output mode
+}
+
+// reportEntry records the outcome of a single file
+type reportEntry struct {
+	File   string // Original file, relative to the input folder
+	Target string // New name, relative to its target folder (empty for skipped files)
+	Reason string // Why the file was skipped or failed
+}
+
+// renameReport collects what was renamed, what was skipped and why
+type renameReport struct {
+	renamed []reportEntry
+	skipped []reportEntry
+	failed  []reportEntry
+}
+
+// display prints the report after all renames are done
+func (r *renameReport) display() {
+	fmt.Println()
+	fmt.Printf("📋	Rename Report: %d renamed, %d skipped, %d failed\n", len(r.renamed), len(r.skipped), len(r.failed))
+	for _, entry := range r.renamed {
+		fmt.Printf("   ✅ %s -> %s\n", entry.File, entry.Target)
+	}
+	for _, entry := range r.skipped {
+		fmt.Printf("   ⏭️ %s: %s\n", entry.File, entry.Reason)
+	}
+	for _, entry := range r.failed {
+		fmt.Printf("   ❌ %s: %s\n", entry.File, entry.Reason)
+	}
+}
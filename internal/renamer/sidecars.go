@@ -0,0 +1,160 @@
+package renamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sidecarRename moves a file belonging to a video along with it
+type sidecarRename struct {
+	Source string // Full path of the original sidecar
+	Target string // Full path of the new sidecar
+}
+
+// videoExtensions are never treated as sidecars of another video
+var videoExtensions = map[string]bool{".mkv": true, ".mp4": true, ".m4v": true, ".avi": true, ".webm": true, ".mov": true, ".ts": true}
+
+// artworkSuffixes are the Kodi style image names of a video, e.g. "Show - 05-thumb.jpg"
+var artworkSuffixes = []string{"-thumb", "-poster", "-fanart", "-landscape"}
+
+// findSidecars returns the files and folders next to a video sharing its base name: subtitles with language tags
+// like "Show - 05.en.ass", NFO files, thumbnails and font folders like "Show - 05.fonts".
+// Files belonging to another video with a longer base name, e.g. "Show - 05.5.en.ass" next to "Show - 05.5.mkv",
+// or starting with a decimal episode part like ".5." are not sidecars of "Show - 05.mkv".
+// The returned names keep the part following the base name, so they can be attached to the new base name.
+func findSidecars(videoPath string) []string {
+	entries, err := os.ReadDir(filepath.Dir(videoPath))
+	if err != nil {
+		return nil
+	}
+
+	videoName := filepath.Base(videoPath)
+	base := strings.TrimSuffix(videoName, filepath.Ext(videoName))
+
+	// Sibling videos whose base name extends this one claim the files starting with their own base name
+	var longerBases []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && videoExtensions[strings.ToLower(filepath.Ext(name))] {
+			if other := strings.TrimSuffix(name, filepath.Ext(name)); len(other) > len(base) && strings.HasPrefix(other, base) {
+				longerBases = append(longerBases, other)
+			}
+		}
+	}
+
+	var sidecars []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == videoName || !strings.HasPrefix(name, base) {
+			continue
+		}
+		if !entry.IsDir() && videoExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		if belongsToOther(name, longerBases) {
+			continue
+		}
+
+		suffix := strings.TrimPrefix(name, base)
+		if (strings.HasPrefix(suffix, ".") && !isDecimalPart(suffix)) || (entry.IsDir() && suffix == "") || isArtwork(suffix) {
+			sidecars = append(sidecars, name)
+		}
+	}
+	sort.Strings(sidecars)
+	return sidecars
+}
+
+// belongsToOther reports whether a file name starts with the base name of another video, e.g. "Show - 05.5.en.ass" for "Show - 05.5"
+func belongsToOther(name string, bases []string) bool {
+	for _, other := range bases {
+		if rest, found := strings.CutPrefix(name, other); found && (rest == "" || strings.HasPrefix(rest, ".") || isArtwork(rest)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDecimalPart reports whether the part following the base name continues a decimal episode number, like ".5.en.ass" of "Show - 05",
+// rather than adding a language tag or extension
+func isDecimalPart(suffix string) bool {
+	part, rest, found := strings.Cut(strings.TrimPrefix(suffix, "."), ".")
+	if !found || part == "" || rest == "" {
+		return false
+	}
+	for _, r := range part {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isArtwork reports whether the part of a file name following the base name marks a thumbnail or poster image
+func isArtwork(suffix string) bool {
+	ext := strings.ToLower(filepath.Ext(suffix))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return false
+	}
+	for _, artwork := range artworkSuffixes {
+		if strings.EqualFold(strings.TrimSuffix(suffix, filepath.Ext(suffix)), artwork) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachSidecars adds the sidecars of every video to the plan, renamed to the new base name of the video.
+// Videos whose sidecars would collide with existing files or other targets of the plan are skipped together with their sidecars.
+func (fr *FileRenamer) attachSidecars(plan []plannedRename, report *renameReport) []plannedRename {
+	// Paths freed by the plan may be reused by sidecars
+	vacated := make(map[string]bool)
+	taken := make(map[string]bool)
+	for i := range plan {
+		plan[i].Sidecars = nil
+		taken[collisionKey(plan[i].Target)] = true
+		if fr.mode.KeepsOriginal() && !plan[i].Displaced {
+			continue
+		}
+		vacated[collisionKey(plan[i].Source)] = true
+		for _, name := range findSidecars(plan[i].Source) {
+			vacated[collisionKey(filepath.Join(filepath.Dir(plan[i].Source), name))] = true
+		}
+	}
+
+	var attached []plannedRename
+	for _, rename := range plan {
+		oldBase := strings.TrimSuffix(filepath.Base(rename.Source), filepath.Ext(rename.Source))
+		newBase := strings.TrimSuffix(rename.Target, filepath.Ext(rename.Target))
+
+		var sidecars []sidecarRename
+		conflict := ""
+		for _, name := range findSidecars(rename.Source) {
+			sidecar := sidecarRename{
+				Source: filepath.Join(filepath.Dir(rename.Source), name),
+				Target: newBase + strings.TrimPrefix(name, oldBase),
+			}
+			key := collisionKey(sidecar.Target)
+			_, err := os.Lstat(sidecar.Target)
+			if taken[key] || (err == nil && !vacated[key]) {
+				conflict = filepath.Base(sidecar.Target)
+				break
+			}
+			sidecars = append(sidecars, sidecar)
+		}
+
+		if conflict != "" {
+			fmt.Printf("⚠️	The sidecar %s of %s is already taken, skipping the file.\n", conflict, filepath.Base(rename.Source))
+			fr.skip(report, rename.Source, "sidecar "+conflict+" already exists")
+			continue
+		}
+		for _, sidecar := range sidecars {
+			taken[collisionKey(sidecar.Target)] = true
+		}
+		rename.Sidecars = sidecars
+		attached = append(attached, rename)
+	}
+	return attached
+}
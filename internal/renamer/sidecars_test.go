@@ -0,0 +1,82 @@
+package renamer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindSidecars(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string // Files next to the video, names ending in "/" are folders
+		video string
+		want  []string
+	}{
+		{
+			name:  "subtitles, nfo and artwork",
+			files: []string{"Show - 05.mkv", "Show - 05.en.ass", "Show - 05.srt", "Show - 05.nfo", "Show - 05-thumb.jpg", "Show - 05.fonts/"},
+			video: "Show - 05.mkv",
+			want:  []string{"Show - 05-thumb.jpg", "Show - 05.en.ass", "Show - 05.fonts", "Show - 05.nfo", "Show - 05.srt"},
+		},
+		{
+			name:  "unrelated files",
+			files: []string{"Show - 05.mkv", "Show - 06.en.ass", "Show - 05 preview.jpg", "Show - 050.srt", "Show.nfo"},
+			video: "Show - 05.mkv",
+			want:  nil,
+		},
+		{
+			name:  "other videos are not sidecars",
+			files: []string{"Show - 05.mkv", "Show - 05.mp4", "Show - 05.en.ass"},
+			video: "Show - 05.mkv",
+			want:  []string{"Show - 05.en.ass"},
+		},
+		{
+			name:  "decimal episode next to the video",
+			files: []string{"Show - 05.mkv", "Show - 05.5.mkv", "Show - 05.en.ass", "Show - 05.5.en.ass", "Show - 05.5-thumb.jpg"},
+			video: "Show - 05.mkv",
+			want:  []string{"Show - 05.en.ass"},
+		},
+		{
+			name:  "decimal episode without its video",
+			files: []string{"Show - 05.mkv", "Show - 05.5.en.ass", "Show - 05.en.ass"},
+			video: "Show - 05.mkv",
+			want:  []string{"Show - 05.en.ass"},
+		},
+		{
+			name:  "sidecars of the decimal episode",
+			files: []string{"Show - 05.mkv", "Show - 05.5.mkv", "Show - 05.en.ass", "Show - 05.5.en.ass"},
+			video: "Show - 05.5.mkv",
+			want:  []string{"Show - 05.5.en.ass"},
+		},
+		{
+			name:  "longer base name claims its sidecars",
+			files: []string{"Show.mkv", "Show.Extended.mkv", "Show.en.srt", "Show.Extended.en.srt"},
+			video: "Show.mkv",
+			want:  []string{"Show.en.srt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				var err error
+				if folder, ok := strings.CutSuffix(name, "/"); ok {
+					err = os.Mkdir(filepath.Join(dir, folder), 0755)
+				} else {
+					err = os.WriteFile(filepath.Join(dir, name), nil, 0644)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := findSidecars(filepath.Join(dir, tt.video)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findSidecars = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package renamer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SpecialsTable maps AniList IDs (optionally combined with an episode number) to S00 episode numbers.
+type SpecialsTable map[string]int
+
+// LoadSpecialsTable loads a specials table from a file.
+// Each line maps an AniList ID, or an AniList ID and episode, to a special episode number:
+//
+//	12345=3     # AniList ID 12345 is S00E03
+//	12345:2=4   # Episode 2 of AniList ID 12345 is S00E04
+//
+// Empty lines and lines starting with # are ignored.
+func LoadSpecialsTable(filePath string) (SpecialsTable, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open specials table: %v", err)
+	}
+	defer file.Close()
+
+	table := make(SpecialsTable)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		// Strip comments and surrounding whitespace
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid specials table entry on line %d: %s", lineNumber, line)
+		}
+
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid special episode number on line %d: %v", lineNumber, err)
+		}
+		table[strings.TrimSpace(key)] = number
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read specials table: %v", err)
+	}
+
+	return table, nil
+}
+
+// Lookup returns the S00 episode number for an AniList ID and episode, preferring episode specific entries.
+func (st SpecialsTable) Lookup(aniListID int, episode string) (int, bool) {
+	if number, ok := st[fmt.Sprintf("%d:%s", aniListID, episode)]; ok && episode != "" {
+		return number, true
+	}
+	number, ok := st[strconv.Itoa(aniListID)]
+	return number, ok
+}
@@ -0,0 +1,221 @@
+package tagging
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tags are the identification results written into a video container
+type Tags struct {
+	Title     string // Episode title, or the show and episode if the episode title is unknown
+	Show      string // Series title
+	EpisodeID string // Episode label, e.g. "5" or "1-2" (empty for movies)
+	Season    int    // Season number, 0 for specials
+	HasSeason bool   // Whether the season is known and written
+	AniListID int    // AniList ID of the match (0 if unknown)
+}
+
+// tag is a single key and value of the container metadata
+type tag struct {
+	Key   string
+	Value string
+}
+
+// fields returns the non-empty tags in the order they are written
+func (t Tags) fields() []tag {
+	var fields []tag
+	add := func(key, value string) {
+		if value != "" {
+			fields = append(fields, tag{Key: key, Value: value})
+		}
+	}
+	add("title", t.Title)
+	add("show", t.Show)
+	add("episode_id", t.EpisodeID)
+	if t.HasSeason {
+		add("season_number", strconv.Itoa(t.Season))
+	}
+	if t.AniListID != 0 {
+		add("ANILIST_ID", strconv.Itoa(t.AniListID))
+	}
+	return fields
+}
+
+// Tagger writes tags into MKV and MP4 files with mkvpropedit or an FFmpeg stream copy
+type Tagger struct {
+	ffmpegPath      string
+	ffprobePath     string
+	mkvpropeditPath string // Empty if mkvpropedit is not installed
+}
+
+// NewTagger creates a Tagger, using mkvpropedit for MKV files if it is found in the PATH.
+func NewTagger(ffmpegPath, ffprobePath string) *Tagger {
+	mkvpropeditPath, _ := exec.LookPath("mkvpropedit")
+	return &Tagger{
+		ffmpegPath:      ffmpegPath,
+		ffprobePath:     ffprobePath,
+		mkvpropeditPath: mkvpropeditPath,
+	}
+}
+
+// Tag writes the tags into a video. The tags are written to a temporary copy next to the video, verified with
+// FFprobe and only then moved over the original, so a failure never leaves a half-written video behind.
+func (t *Tagger) Tag(path string, tags Tags) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".mkv" && ext != ".mp4" && ext != ".m4v" {
+		return fmt.Errorf("writing tags is only supported for MKV and MP4 files: %s", filepath.Base(path))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	temp := filepath.Join(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+".tagging"+filepath.Ext(path))
+	defer os.Remove(temp)
+
+	if ext == ".mkv" && t.mkvpropeditPath != "" {
+		err = t.tagWithMkvpropedit(path, temp, tags)
+	} else {
+		err = t.tagWithFFmpeg(path, temp, tags)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := t.verify(temp, tags); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// tagWithFFmpeg remuxes the video into the temporary file without re-encoding, adding the tags
+func (t *Tagger) tagWithFFmpeg(path, temp string, tags Tags) error {
+	args := []string{"-v", "error", "-y", "-i", path, "-map", "0", "-c", "copy"}
+	for _, field := range tags.fields() {
+		args = append(args, "-metadata", field.Key+"="+field.Value)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".mkv") {
+		args = append(args, "-f", "matroska")
+	} else {
+		args = append(args, "-movflags", "use_metadata_tags", "-f", "mp4") // Keep custom tags like ANILIST_ID in MP4
+	}
+	args = append(args, temp)
+
+	if output, err := exec.Command(t.ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write tags with ffmpeg: %v\nFFmpeg Output:\n%s", err, string(output))
+	}
+	return nil
+}
+
+// matroskaTags is the global tag file format understood by mkvpropedit
+type matroskaTags struct {
+	XMLName xml.Name `xml:"Tags"`
+	Tag     struct {
+		Targets struct {
+			TargetTypeValue int `xml:"TargetTypeValue"`
+		} `xml:"Targets"`
+		Simple []matroskaSimpleTag `xml:"Simple"`
+	} `xml:"Tag"`
+}
+
+// matroskaSimpleTag is a single tag of a Matroska tag file
+type matroskaSimpleTag struct {
+	Name   string `xml:"Name"`
+	String string `xml:"String"`
+}
+
+// tagWithMkvpropedit copies the video to the temporary file and edits its header in place
+func (t *Tagger) tagWithMkvpropedit(path, temp string, tags Tags) error {
+	if err := copyFile(path, temp); err != nil {
+		return fmt.Errorf("failed to copy video for tagging: %v", err)
+	}
+
+	// The title belongs to the segment info, all other tags are global tags
+	var tagFile matroskaTags
+	tagFile.Tag.Targets.TargetTypeValue = 50
+	title := ""
+	for _, field := range tags.fields() {
+		if field.Key == "title" {
+			title = field.Value
+			continue
+		}
+		tagFile.Tag.Simple = append(tagFile.Tag.Simple, matroskaSimpleTag{Name: strings.ToUpper(field.Key), String: field.Value})
+	}
+
+	data, err := xml.MarshalIndent(tagFile, "", "  ")
+	if err != nil {
+		return err
+	}
+	tagsPath := temp + ".xml"
+	if err := os.WriteFile(tagsPath, append([]byte(xml.Header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write tag file: %v", err)
+	}
+	defer os.Remove(tagsPath)
+
+	args := []string{temp, "--tags", "global:" + tagsPath}
+	if title != "" {
+		args = append(args, "--edit", "info", "--set", "title="+title)
+	}
+	if output, err := exec.Command(t.mkvpropeditPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write tags with mkvpropedit: %v\nOutput:\n%s", err, string(output))
+	}
+	return nil
+}
+
+// verify reads the tags back with FFprobe and checks that every tag was written
+func (t *Tagger) verify(path string, tags Tags) error {
+	output, err := exec.Command(t.ffprobePath, "-v", "error", "-show_entries", "format_tags", "-of", "json", path).Output()
+	if err != nil {
+		return fmt.Errorf("failed to read tags with ffprobe: %v", err)
+	}
+
+	var probe struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+
+	// Containers differ in the case of their tag names
+	written := make(map[string]string)
+	for key, value := range probe.Format.Tags {
+		written[strings.ToLower(key)] = value
+	}
+	for _, field := range tags.fields() {
+		if value, ok := written[strings.ToLower(field.Key)]; !ok || value != field.Value {
+			return fmt.Errorf("tag %s was not written correctly (expected %q, found %q)", field.Key, field.Value, value)
+		}
+	}
+	return nil
+}
+
+// copyFile copies the contents of a file
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
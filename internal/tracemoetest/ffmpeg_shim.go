@@ -0,0 +1,76 @@
+// internal/tracemoetest/ffmpeg_shim.go
+package tracemoetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// FrameMagic starts the content of every frame written by the FFmpeg shim, followed by
+// "video=<video path> time=<timestamp>", so scripted rules can match frames by video name.
+const FrameMagic = "FUMOFAKEFRAME"
+
+// ffmpegShim writes a fake frame instead of decoding the video.
+// The extractor calls: ffmpeg -ss <timestamp> -i <video> -frames:v 1 -q:v 2 <output>
+const ffmpegShim = `#!/bin/sh
+# FumoFinder FFmpeg shim: writes a fake frame naming the video and timestamp
+ts=""; input=""; output=""
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -ss) ts="$2"; shift ;;
+    -i) input="$2"; shift ;;
+  esac
+  output="$1"
+  shift
+done
+printf '` + FrameMagic + ` video=%s time=%s\n' "$input" "$ts" > "$output"
+`
+
+// ffprobeShim reports the same duration for every video
+const ffprobeShim = `#!/bin/sh
+# FumoFinder FFprobe shim: reports a fixed duration
+echo %s
+`
+
+// Windows variants of the shims, the arguments are at fixed positions
+const ffmpegShimWindows = "@echo off\r\nrem FumoFinder FFmpeg shim: writes a fake frame naming the video and timestamp\r\necho " + FrameMagic + " video=%~4 time=%~2> \"%~9\"\r\n"
+const ffprobeShimWindows = "@echo off\r\nrem FumoFinder FFprobe shim: reports a fixed duration\r\necho %s\r\n"
+
+// WriteFFmpegShim writes fake ffmpeg and ffprobe executables to dir and returns their paths.
+// ffprobe reports the given duration in seconds for every video, ffmpeg writes text frames starting with FrameMagic.
+func WriteFFmpegShim(dir string, duration float64) (ffmpegPath, ffprobePath string, err error) {
+	ffmpegScript, ffprobeScript, extension := ffmpegShim, ffprobeShim, ""
+	if runtime.GOOS == "windows" {
+		ffmpegScript, ffprobeScript, extension = ffmpegShimWindows, ffprobeShimWindows, ".cmd"
+	}
+
+	ffmpegPath = filepath.Join(dir, "ffmpeg"+extension)
+	ffprobePath = filepath.Join(dir, "ffprobe"+extension)
+
+	if err := os.WriteFile(ffmpegPath, []byte(ffmpegScript), 0755); err != nil {
+		return "", "", fmt.Errorf("failed to write ffmpeg shim: %v", err)
+	}
+	ffprobeScript = fmt.Sprintf(ffprobeScript, strconv.FormatFloat(duration, 'f', 3, 64))
+	if err := os.WriteFile(ffprobePath, []byte(ffprobeScript), 0755); err != nil {
+		return "", "", fmt.Errorf("failed to write ffprobe shim: %v", err)
+	}
+
+	return ffmpegPath, ffprobePath, nil
+}
+
+// WriteVideos creates empty video files in dir, to be picked up by the extractor together with the FFmpeg shim
+func WriteVideos(dir string, names ...string) error {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create folder for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return fmt.Errorf("failed to create video %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// internal/tracemoetest/fixtures.go
+package tracemoetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WhereIsF1/FumoFinder/internal/model" // Import the model package for TraceMoeResponse
+)
+
+// Response is a scripted response of the fake server
+type Response struct {
+	Status int           // HTTP status code (default: 200)
+	Body   []byte        // Raw response body, sent as is (used for malformed responses)
+	Result any           // Value encoded as JSON body if Body is nil
+	Delay  time.Duration // Time to wait before responding, to simulate slow responses and timeouts
+	Header http.Header   // Additional response headers, e.g. Retry-After
+
+	results []model.TraceMoeResult // Search results, filtered by the anilistID parameter before encoding
+	found   bool                   // Whether the response is a successful search response
+}
+
+// AtFrameTime can be passed as the scene start of Match to place the match at the timestamp of the frame written by the FFmpeg shim
+const AtFrameTime = -1.0
+
+// Match creates a search result for an episode of a show
+func Match(aniListID int, title string, episode int, from, similarity float64) model.TraceMoeResult {
+	return model.TraceMoeResult{
+		Anilist: model.AnilistInfo{
+			ID:    aniListID,
+			Title: model.Title{Romaji: title, English: title},
+		},
+		Filename:   title + " - " + strconv.Itoa(episode) + ".mkv",
+		Episode:    model.EpisodeNumber{Number: float64(episode), Raw: strconv.Itoa(episode)},
+		From:       from,
+		To:         from + 1,
+		Similarity: similarity,
+	}
+}
+
+// Found responds with the given search results
+func Found(results ...model.TraceMoeResult) Response {
+	return Response{Status: http.StatusOK, results: results, found: true}
+}
+
+// Error responds with the status code and an error message in the format used by trace.moe
+func Error(status int, message string) Response {
+	return Response{Status: status, Result: map[string]string{"error": message}}
+}
+
+// TooManyRequests responds like trace.moe when the concurrency or rate limit is exceeded
+func TooManyRequests() Response {
+	return Error(http.StatusTooManyRequests, "Concurrency limit exceeded")
+}
+
+// ServerError responds with an internal server error
+func ServerError() Response {
+	return Error(http.StatusInternalServerError, "Internal Server Error")
+}
+
+// QuotaDepleted responds like trace.moe when the search quota is used up
+func QuotaDepleted() Response {
+	return Error(http.StatusPaymentRequired, "Search quota depleted")
+}
+
+// Malformed responds with a truncated JSON body
+func Malformed() Response {
+	return Response{Status: http.StatusOK, Body: []byte(`{"frameCount": 1, "result": [{"anilist": `)}
+}
+
+// Slow delays a response, e.g. to exceed the client timeout
+func Slow(delay time.Duration, response Response) Response {
+	response.Delay = delay
+	return response
+}
+
+// RetryAfter asks the client to wait the given number of seconds before retrying, e.g. with TooManyRequests
+func RetryAfter(seconds int, response Response) Response {
+	header := response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Retry-After", strconv.Itoa(seconds))
+	response.Header = header
+	return response
+}
+
+// searchBody creates the body of a successful search response
+func searchBody(results []model.TraceMoeResult) map[string]any {
+	encoded := make([]map[string]any, 0, len(results))
+	for _, result := range results {
+		var episode any // Movies have no episode number
+		if result.Episode.Number != 0 {
+			episode = result.Episode.Number
+		}
+		encoded = append(encoded, map[string]any{
+			"anilist": map[string]any{
+				"id":       result.Anilist.ID,
+				"idMal":    result.Anilist.IDMal,
+				"title":    result.Anilist.Title,
+				"synonyms": result.Anilist.Synonyms,
+				"isAdult":  result.Anilist.IsAdult,
+			},
+			"filename":   result.Filename,
+			"episode":    episode,
+			"from":       result.From,
+			"to":         result.To,
+			"similarity": result.Similarity,
+			"video":      result.Video,
+			"image":      result.Image,
+		})
+	}
+	return map[string]any{"frameCount": 1000, "error": "", "result": encoded}
+}
+
+// write sends the response, waiting for its delay first. Search results are restricted to the AniList ID
+// if the request was filtered (0 - no filter), like trace.moe does on the server, and matches at AtFrameTime
+// are moved to the frame timestamp.
+func (r Response) write(w http.ResponseWriter, aniListID int, frameTime float64) {
+	if r.Delay > 0 {
+		time.Sleep(r.Delay)
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body := r.Body
+	if body == nil && r.found {
+		var results []model.TraceMoeResult
+		for _, result := range r.results {
+			if aniListID != 0 && result.Anilist.ID != aniListID {
+				continue
+			}
+			if result.From == AtFrameTime {
+				result.From, result.To = frameTime, frameTime+1
+			}
+			results = append(results, result)
+		}
+		body, _ = json.Marshal(searchBody(results))
+	} else if body == nil {
+		body, _ = json.Marshal(r.Result)
+	}
+
+	for key, values := range r.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,84 @@
+// internal/tracemoetest/proxy.go
+package tracemoetest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Proxy is a forwarding HTTP proxy that can be switched to fail, to exercise the proxy checker and the proxy failover
+type Proxy struct {
+	server    *httptest.Server
+	transport *http.Transport // Transport without proxy settings from the environment
+
+	mu       sync.Mutex
+	failing  bool
+	requests int
+}
+
+// NewProxy starts a forwarding HTTP proxy
+func NewProxy() *Proxy {
+	p := &Proxy{transport: &http.Transport{}}
+	p.server = httptest.NewServer(http.HandlerFunc(p.handle))
+	return p
+}
+
+// URL returns the proxy address as used in the proxy file
+func (p *Proxy) URL() string {
+	return p.server.URL
+}
+
+// Close shuts the proxy down
+func (p *Proxy) Close() {
+	p.server.Close()
+	p.transport.CloseIdleConnections()
+}
+
+// SetFailing makes the proxy answer every request with 502 Bad Gateway
+func (p *Proxy) SetFailing(failing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failing = failing
+}
+
+// Requests returns the number of requests received by the proxy
+func (p *Proxy) Requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+// handle forwards a proxied request to its target
+func (p *Proxy) handle(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests++
+	failing := p.failing
+	p.mu.Unlock()
+
+	if failing || !r.URL.IsAbs() {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return
+	}
+
+	outgoing := r.Clone(r.Context())
+	outgoing.RequestURI = ""
+	outgoing.Header.Del("Proxy-Authorization")
+	outgoing.Header.Del("Proxy-Connection")
+
+	resp, err := p.transport.RoundTrip(outgoing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
@@ -0,0 +1,217 @@
+// internal/tracemoetest/server.go
+
+// Package tracemoetest provides a fake trace.moe server, a forwarding proxy and an FFmpeg shim,
+// so the identification, proxy checking and renaming can be exercised without network access.
+package tracemoetest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/WhereIsF1/FumoFinder/internal/backend" // Import the backend package for TraceMoe and Quota
+)
+
+// Request records a request received by the fake server
+type Request struct {
+	Path   string     // Request path, /search or /me
+	Query  url.Values // Query parameters, e.g. anilistID and cutBorders
+	APIKey string     // Value of the x-trace-key header
+	Frame  []byte     // Uploaded frame, from a raw body, a multipart form or fetched by URL
+}
+
+// rule scripts the responses to frames whose content contains a marker
+type rule struct {
+	contains  []byte
+	responses []Response // Responses in order; the last one is repeated
+	served    int
+}
+
+// Server is a fake trace.moe instance serving /search and /me from scripted fixtures
+type Server struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	rules      []*rule
+	fallback   Response
+	meScript   []Response // Scripted /me responses in order, before the quota is reported
+	quota      backend.Quota
+	requests   []Request
+	frameFetch *http.Client // Client used to fetch frames in the url upload mode
+}
+
+// NewServer starts a fake trace.moe server. By default every search succeeds without results
+// and the quota allows 1000 searches.
+func NewServer() *Server {
+	s := &Server{
+		fallback:   Found(),
+		quota:      backend.Quota{ID: "127.0.0.1", Priority: 0, Concurrency: 1, Quota: 1000},
+		frameFetch: &http.Client{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/me", s.handleMe)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the base URL of the server, to be used as self-hosted trace.moe base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Backend creates a trace.moe backend pointing at the server
+func (s *Server) Backend(config backend.TraceMoeConfig) *backend.TraceMoe {
+	return backend.NewSelfHostedTraceMoe(s.URL(), config)
+}
+
+// OnFrame scripts the responses to frames containing the marker, e.g. the video name written by the FFmpeg shim.
+// The responses are served in order and the last one is repeated.
+func (s *Server) OnFrame(marker string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, &rule{contains: []byte(marker), responses: responses})
+}
+
+// SetFallback sets the response to frames without a matching rule
+func (s *Server) SetFallback(response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = response
+}
+
+// SetQuota sets the quota reported by /me. Once all searches are used up, searches fail with 402.
+func (s *Server) SetQuota(quota, used int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quota.Quota = quota
+	s.quota.QuotaUsed = used
+}
+
+// OnMe scripts the responses of /me in order; afterwards the quota is reported
+func (s *Server) OnMe(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meScript = append(s.meScript, responses...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Searches returns the number of search requests received so far
+func (s *Server) Searches() int {
+	count := 0
+	for _, request := range s.Requests() {
+		if request.Path == "/search" {
+			count++
+		}
+	}
+	return count
+}
+
+// handleSearch serves /search from the scripted rules
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	frame, err := s.readFrame(r)
+	if err != nil {
+		Error(http.StatusBadRequest, err.Error()).write(w, 0, 0)
+		return
+	}
+	aniListID, _ := strconv.Atoi(r.URL.Query().Get("anilistID"))
+
+	s.mu.Lock()
+	s.record(r, frame)
+	if s.quota.Quota > 0 && s.quota.QuotaUsed >= s.quota.Quota {
+		s.mu.Unlock()
+		QuotaDepleted().write(w, aniListID, 0)
+		return
+	}
+
+	response := s.fallback
+	for _, rule := range s.rules {
+		if bytes.Contains(frame, rule.contains) {
+			response = rule.responses[min(rule.served, len(rule.responses)-1)]
+			rule.served++
+			break
+		}
+	}
+	if response.Status == 0 || response.Status == http.StatusOK {
+		s.quota.QuotaUsed++ // Only successful searches count towards the quota
+	}
+	s.mu.Unlock()
+
+	response.write(w, aniListID, frameTime(frame))
+}
+
+// handleMe serves /me from the scripted responses or the quota
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.record(r, nil)
+	var response Response
+	if len(s.meScript) > 0 {
+		response = s.meScript[0]
+		s.meScript = s.meScript[1:]
+	} else {
+		response = Response{Status: http.StatusOK, Result: s.quota}
+	}
+	s.mu.Unlock()
+
+	response.write(w, 0, 0)
+}
+
+// record stores a received request; the caller must hold the lock
+func (s *Server) record(r *http.Request, frame []byte) {
+	s.requests = append(s.requests, Request{
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		APIKey: r.Header.Get("x-trace-key"),
+		Frame:  frame,
+	})
+}
+
+// frameTime returns the timestamp written into a frame by the FFmpeg shim, or 0 for other frames
+func frameTime(frame []byte) float64 {
+	_, after, found := bytes.Cut(frame, []byte(" time="))
+	if !found {
+		return 0
+	}
+	seconds, _ := strconv.ParseFloat(strings.TrimSpace(string(after)), 64)
+	return seconds
+}
+
+// readFrame reads the uploaded frame in any of the upload modes
+func (s *Server) readFrame(r *http.Request) ([]byte, error) {
+	if frameURL := r.URL.Query().Get("url"); frameURL != "" {
+		resp, err := s.frameFetch.Get(frameURL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(resp.Body)
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+
+	return io.ReadAll(r.Body)
+}